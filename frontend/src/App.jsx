@@ -1,21 +1,83 @@
 import { useState } from "react";
+import axios from "axios";
 import UsernameForm from "./components/UsernameForm";
 import GameBoard from "./components/GameBoard";
 import Leaderboard from "./components/Leaderboard";
 import { useGameSocket } from "./hooks/useGameSocket";
 import "./styles/index.css";
 
+const backendUrl = import.meta.env.VITE_BACKEND_URL || "http://localhost:9090";
+
 export default function App() {
+  // username is what the server calls us: an account name, or "guest-<name>"
   const [username, setUsername] = useState("");
-  const { status, gameState, sendMove, opponent } = useGameSocket(username);
+  const [token, setToken] = useState("");
+  const [room, setRoom] = useState("");
+  const [loginError, setLoginError] = useState("");
+  const { status, gameState, sendMove, send, opponent, roomCode, drawOffer, rematchOffer } =
+    useGameSocket(username, room, token);
+
+  const start = async ({ name, password, register }, roomValue = "") => {
+    if (!name) return;
+    setLoginError("");
+    if (password) {
+      try {
+        const res = await axios.post(`${backendUrl}/auth/${register ? "register" : "login"}`, {
+          username: name,
+          password,
+        });
+        setToken(res.data.token);
+        setUsername(res.data.username);
+      } catch (err) {
+        setLoginError(err.response?.data || "Couldn't reach the server");
+        return;
+      }
+    } else {
+      setToken("");
+      setUsername(`guest-${name}`);
+    }
+    setRoom(roomValue);
+  };
 
-  if (!username) return <UsernameForm onSubmit={setUsername} />;
+  if (!username) return <UsernameForm onSubmit={start} error={loginError} />;
 
   return (
     <div style={{ textAlign: "center" }}>
       <h1>4 in a Row</h1>
       <p>Status: {status}</p>
-      {opponent && <p>Opponent: {opponent}</p>}
+      {roomCode && status === "waiting" && (
+        <p>
+          Share this room code with your friend: <strong>{roomCode}</strong>
+        </p>
+      )}
+      {opponent && (
+        <p>
+          Opponent: {opponent}
+          {gameState.rated === false && " (unrated)"}
+        </p>
+      )}
+      {status === "playing" && (
+        <p>
+          <button onClick={() => send("resign")}>Resign</button>{" "}
+          {drawOffer && drawOffer !== username ? (
+            <>
+              {drawOffer} offers a draw{" "}
+              <button onClick={() => send("acceptDraw")}>Accept</button>{" "}
+              <button onClick={() => send("declineDraw")}>Decline</button>
+            </>
+          ) : (
+            <button disabled={drawOffer === username} onClick={() => send("offerDraw")}>
+              {drawOffer === username ? "Draw offered" : "Offer draw"}
+            </button>
+          )}
+        </p>
+      )}
+      {status === "ended" && (
+        <p>
+          {rematchOffer && rematchOffer !== username && <>{rematchOffer} wants a rematch </>}
+          <button onClick={() => send("rematch")}>Rematch</button>
+        </p>
+      )}
       {gameState.board && (
         <GameBoard
           board={gameState.board}
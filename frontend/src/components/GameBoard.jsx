@@ -8,7 +8,7 @@ export default function GameBoard({ board, color, turn, onMove }) {
       </h2>
       <h3>Turn: {turn}</h3>
 
-      <div className="board">
+      <div className="board" style={{ gridTemplateColumns: `repeat(${board[0].length}, 70px)` }}>
         {board.map((row, rIndex) =>
           row.map((cell, cIndex) => (
             <div
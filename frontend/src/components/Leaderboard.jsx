@@ -24,6 +24,7 @@ export default function Leaderboard() {
         <thead>
           <tr>
             <th>User</th>
+            <th>Rating</th>
             <th>Wins</th>
             <th>Draws</th>
             <th>Losses</th>
@@ -33,6 +34,9 @@ export default function Leaderboard() {
           {players.map((p, i) => (
             <tr key={i}>
               <td>{p.username}</td>
+              <td>
+                {Math.round(p.rating)} <small>±{Math.round(2 * p.rd)}</small>
+              </td>
               <td>{p.wins}</td>
               <td>{p.draws}</td>
               <td>{p.losses}</td>
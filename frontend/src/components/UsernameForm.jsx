@@ -1,7 +1,12 @@
 import { useState } from "react";
 
-export default function UsernameForm({ onSubmit }) {
+// Leave the password empty to play as a guest (unrated).
+export default function UsernameForm({ onSubmit, error }) {
   const [name, setName] = useState("");
+  const [password, setPassword] = useState("");
+  const [code, setCode] = useState("");
+  const inputStyle = { padding: "10px", borderRadius: "8px", border: "none", marginRight: "8px" };
+  const submit = (room = "", register = false) => onSubmit({ name: name.trim(), password, register }, room);
   return (
     <div style={{ textAlign: "center" }}>
       <h1>🎯 4 in a Row</h1>
@@ -9,9 +14,32 @@ export default function UsernameForm({ onSubmit }) {
         value={name}
         onChange={(e) => setName(e.target.value)}
         placeholder="Enter your username"
-        style={{ padding: "10px", borderRadius: "8px", border: "none", marginRight: "8px" }}
+        style={inputStyle}
       />
-      <button onClick={() => onSubmit(name.trim())}>Start</button>
+      <input
+        type="password"
+        value={password}
+        onChange={(e) => setPassword(e.target.value)}
+        placeholder="Password (empty = guest)"
+        style={inputStyle}
+      />
+      <button onClick={() => submit()}>Start</button>{" "}
+      <button disabled={!password} onClick={() => submit("", true)}>
+        Register
+      </button>
+      {error && <p style={{ color: "salmon" }}>{error}</p>}
+      <div style={{ marginTop: "16px" }}>
+        <button onClick={() => submit("create")} style={{ marginRight: "8px" }}>
+          Create private room
+        </button>
+        <input
+          value={code}
+          onChange={(e) => setCode(e.target.value.toUpperCase())}
+          placeholder="Room code"
+          style={{ ...inputStyle, width: "110px" }}
+        />
+        <button onClick={() => code.trim() && submit(code.trim())}>Join room</button>
+      </div>
     </div>
   );
 }
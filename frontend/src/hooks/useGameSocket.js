@@ -1,17 +1,43 @@
 import { useEffect, useState, useRef } from "react";
 
-export function useGameSocket(username) {
+const RESUME_KEY = "fourinarow.resume";
+
+// username is who we play as; without a token we connect as a guest, and
+// the server calls us "guest-<name>"
+export function useGameSocket(username, room = "", token = "") {
   const backendUrl = import.meta.env.VITE_BACKEND_URL || "http://localhost:9090";
   const WS_URL = backendUrl.replace("http", "ws") + "/ws";
+  // protocol version we speak, see protocol/schema.json
+  const PROTOCOL_VERSION = 1;
   const [socket, setSocket] = useState(null);
   const [gameState, setGameState] = useState({ board: [], turn: null, color: null });
   const [status, setStatus] = useState("connecting");
   const [opponent, setOpponent] = useState(null);
+  const [roomCode, setRoomCode] = useState(null);
+  // username of whoever has an open draw offer / asked for a rematch
+  const [drawOffer, setDrawOffer] = useState(null);
+  const [rematchOffer, setRematchOffer] = useState(null);
   const gameIdRef = useRef(null);
+  const colorRef = useRef(null);
+  // seq numbers the server echoes back on a rejection, and what each was
+  const seqRef = useRef(0);
+  const sentRef = useRef({});
+  // bumped to connect again without the resume token once it's no good
+  const [attempt, setAttempt] = useState(0);
 
   useEffect(() => {
     if (!username) return;
-    const ws = new WebSocket(`${WS_URL}?username=${username}`);
+    // a reload mid-game gets back into it with the token from start
+    const resume = sessionStorage.getItem(RESUME_KEY);
+    const roomParam = room ? `&room=${encodeURIComponent(room)}` : "";
+    const who = token
+      ? `token=${encodeURIComponent(token)}`
+      : `guest=${encodeURIComponent(username.replace(/^guest-/, ""))}`;
+    const ws = new WebSocket(
+      resume
+        ? `${WS_URL}?v=${PROTOCOL_VERSION}&resume=${encodeURIComponent(resume)}`
+        : `${WS_URL}?v=${PROTOCOL_VERSION}&${who}${roomParam}`
+    );
     setSocket(ws);
 
     ws.onopen = () => setStatus("waiting");
@@ -21,17 +47,51 @@ export function useGameSocket(username) {
         case "queued":
           setStatus("waiting");
           break;
+        case "roomCreated":
+          setStatus("waiting");
+          setRoomCode(data.code);
+          break;
+        case "error": {
+          if (resume && (data.code === "expired" || data.code === "forbidden")) {
+            // that game is over; start afresh
+            sessionStorage.removeItem(RESUME_KEY);
+            setAttempt((n) => n + 1);
+            break;
+          }
+          const what = data.seq && sentRef.current[data.seq];
+          alert(what ? `${what} rejected: ${data.message}` : data.message);
+          break;
+        }
         case "start":
           setStatus("playing");
           setOpponent(data.opponent);
+          setDrawOffer(null);
+          setRematchOffer(null);
           gameIdRef.current = data.gameId;
-          setGameState({ board: data.board, color: data.color, turn: data.turn });
+          colorRef.current = data.color;
+          sessionStorage.setItem(RESUME_KEY, data.resume);
+          setGameState({ board: data.board, color: data.color, turn: data.turn, rated: data.rated });
           break;
         case "update":
           setGameState((s) => ({ ...s, board: data.board, turn: data.turn }));
+          // moving instead of answering an offer declines it
+          setDrawOffer((by) =>
+            by && (by === username) === (data.move.player === colorRef.current) ? by : null
+          );
+          break;
+        case "drawOffered":
+          setDrawOffer(data.by);
+          break;
+        case "drawDeclined":
+          setDrawOffer(null);
+          break;
+        case "rematchOffered":
+          setRematchOffer(data.by);
           break;
         case "gameOver":
           setStatus("ended");
+          setDrawOffer(null);
+          sessionStorage.removeItem(RESUME_KEY);
           alert(data.result);
           break;
         case "info":
@@ -40,7 +100,10 @@ export function useGameSocket(username) {
         case "rejoined":
           setStatus("rejoined");
           setOpponent(data.opponent);
-          setGameState({ board: data.board, color: data.color, turn: data.turn });
+          gameIdRef.current = data.gameId;
+          colorRef.current = data.color;
+          sessionStorage.setItem(RESUME_KEY, data.resume);
+          setGameState({ board: data.board, color: data.color, turn: data.turn, rated: data.rated });
           break;
         default:
           break;
@@ -48,13 +111,24 @@ export function useGameSocket(username) {
     };
     ws.onclose = () => setStatus("disconnected");
     return () => ws.close();
-  }, [username]);
+  }, [username, room, token, attempt]);
+
+  const sendMsg = (msg, what) => {
+    const seq = ++seqRef.current;
+    sentRef.current[seq] = what;
+    socket.send(JSON.stringify({ ...msg, seq }));
+  };
 
   const sendMove = (col) => {
     if (socket && status === "playing") {
-      socket.send(JSON.stringify({ type: "move", col }));
+      sendMsg({ type: "move", col }, `Move in column ${col + 1}`);
     }
   };
 
-  return { status, gameState, sendMove, opponent };
+  // resign, offerDraw, acceptDraw, declineDraw, rematch
+  const send = (type) => {
+    if (socket) sendMsg({ type }, type);
+  };
+
+  return { status, gameState, sendMove, send, opponent, roomCode, drawOffer, rematchOffer };
 }
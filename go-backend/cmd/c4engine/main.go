@@ -0,0 +1,95 @@
+// c4engine wraps the built-in bot in the external engine protocol (see
+// internal/game/engine_process.go). It's the reference for people writing
+// engines in other languages, and handy for testing the adapter:
+//
+//	EXTERNAL_ENGINES="ref=go run ./cmd/c4engine -level hard" go run ./cmd/server
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/game"
+)
+
+func main() {
+	level := flag.String("level", "hard", "Bot level: easy, medium, hard, expert, perfect")
+	flag.Parse()
+
+	d, err := game.ParseDifficulty(*level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	bot := game.Bot{Level: d}
+
+	out := bufio.NewWriter(os.Stdout)
+	say := func(format string, args ...any) {
+		fmt.Fprintf(out, format+"\n", args...)
+		out.Flush()
+	}
+
+	variant := game.Standard
+	var pos *game.GameLogic
+	turn := "R"
+
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		f := strings.Fields(in.Text())
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case "c4i":
+			say("id name c4engine (%s)", d)
+			say("c4iok")
+		case "isready":
+			say("readyok")
+		case "newgame":
+			if len(f) == 4 {
+				rows, _ := strconv.Atoi(f[1])
+				cols, _ := strconv.Atoi(f[2])
+				connect, _ := strconv.Atoi(f[3])
+				variant = game.Variant{Rows: rows, Cols: cols, Connect: connect}
+			}
+		case "position":
+			// position <pos> turn <R|Y>
+			if len(f) < 2 {
+				continue
+			}
+			p, err := game.ParsePosition(f[1], variant.Connect)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "position:", err)
+				continue
+			}
+			pos, turn = p, p.ToMove()
+			if len(f) == 4 && f[2] == "turn" {
+				turn = f[3]
+			}
+		case "go":
+			if pos == nil {
+				continue
+			}
+			movetime := time.Second
+			if len(f) == 3 && f[1] == "movetime" {
+				if ms, err := strconv.Atoi(f[2]); err == nil {
+					movetime = time.Duration(ms) * time.Millisecond
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), movetime)
+			col, _ := bot.ChooseMove(ctx, pos, turn, 0)
+			cancel()
+			say("bestmove %d", col)
+		case "stop":
+			// searches are synchronous, nothing to interrupt
+		case "quit":
+			return
+		}
+	}
+}
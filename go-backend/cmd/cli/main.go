@@ -2,41 +2,32 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/auth"
+	"github.com/yourname/fourinarow/internal/game"
+	"github.com/yourname/fourinarow/internal/protocol"
 )
 
-type StartMsg struct {
-	Type     string        `json:"type"`
-	GameID   string        `json:"gameId"`
-	Color    string        `json:"color"`
-	Opponent string        `json:"opponent"`
-	Board    [][]*string   `json:"board"`
-	Turn     string        `json:"turn"`
-}
-type UpdateMsg struct {
-	Type  string        `json:"type"`
-	Move  struct {
-		Row    int    `json:"row"`
-		Col    int    `json:"col"`
-		Player string `json:"player"`
-	} `json:"move"`
-	Board [][]*string `json:"board"`
-	Turn  string      `json:"turn"`
-}
-type SimpleMsg struct {
-	Type   string `json:"type"`
-	Result string `json:"result,omitempty"`
-	Msg    string `json:"message,omitempty"`
-	Color  string `json:"color,omitempty"`
-	Turn   string `json:"turn,omitempty"`
+func fmtClock(c *protocol.Clock) string {
+	ms := func(ms int64) string {
+		return fmt.Sprintf("%d:%04.1f", ms/60000, float64(ms%60000)/1000)
+	}
+	return fmt.Sprintf("⏱  R %s | Y %s", ms(c.R), ms(c.Y))
 }
 
 func printBoard(board [][]*string) {
@@ -52,65 +43,202 @@ func printBoard(board [][]*string) {
 		}
 		fmt.Println("|")
 	}
-	fmt.Println("  0  1  2  3  4  5  6")
+	if len(board) > 0 {
+		for c := 0; c < len(board[0]); c++ {
+			fmt.Printf("%3d", c)
+		}
+		fmt.Println()
+	}
 	fmt.Println()
 }
 
 func firstPlayableCol(board [][]*string) int {
 	if len(board) == 0 {
-		return 3
+		return 0
 	}
 	// prefer center outward
-	order := []int{3, 2, 4, 1, 5, 0, 6}
-	for _, c := range order {
+	for _, c := range game.CenterOrder(len(board[0])) {
 		if board[0][c] == nil {
 			return c
 		}
 	}
-	// fallback
-	for c := 0; c < len(board[0]); c++ {
-		if board[0][c] == nil {
-			return c
-		}
+	return 0
+}
+
+// login gets a session token from the server behind wsURL, registering
+// the account first if asked to.
+func login(wsURL, user, password string, register bool) (string, error) {
+	base := strings.TrimSuffix(strings.Replace(wsURL, "ws", "http", 1), "/ws")
+	path := "/auth/login"
+	if register {
+		path = "/auth/register"
 	}
-	return 3
+	body, _ := json.Marshal(map[string]string{"username": user, "password": password})
+	resp, err := http.Post(base+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("%s", strings.TrimSpace(string(msg)))
+	}
+	var sess auth.Session
+	if err := json.NewDecoder(resp.Body).Decode(&sess); err != nil {
+		return "", err
+	}
+	return sess.Token, nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
 	server := flag.String("server", "ws://localhost:9090/ws", "WebSocket server URL")
-	user := flag.String("user", "", "Username")
+	user := flag.String("user", "", "Username; without a password you play as a guest, unrated")
+	password := flag.String("password", os.Getenv("FOURINAROW_PASSWORD"), "Account password (default $FOURINAROW_PASSWORD)")
+	register := flag.Bool("register", false, "Create the -user account with -password before playing")
 	auto := flag.Bool("auto", false, "Auto-play moves when it's your turn")
+	rows := flag.Int("rows", 0, "Board rows (0 = server default)")
+	cols := flag.Int("cols", 0, "Board columns (0 = server default)")
+	connect := flag.Int("connect", 0, "Discs in a row needed to win (0 = server default)")
+	difficulty := flag.String("difficulty", "", "Bot level if matched with the bot: easy, medium, hard, expert, perfect")
+	engine := flag.String("engine", "", "Registered engine to play if matched with the bot (overrides -difficulty)")
+	room := flag.String("room", "", "Private room: \"create\" for a new one, or a code to join")
+	engineCmd := flag.String("engine-cmd", "", "External engine command to auto-play your moves (implies -auto)")
+	timeControl := flag.String("time", "", "Time control: \"60+2\" (base+increment seconds) or \"15/move\"; empty = untimed")
+	spectate := flag.String("spectate", "", "Watch a live game by id instead of playing (no -user needed)")
+	resume := flag.String("resume", "", "Get back into a game with the token it gave you (no -user needed)")
 	flag.Parse()
 
-	if strings.TrimSpace(*user) == "" {
-		log.Fatal("provide -user <name>")
+	if *spectate == "" && *resume == "" && strings.TrimSpace(*user) == "" {
+		log.Fatal("provide -user <name>, -resume <token> or -spectate <gameId>")
 	}
 
-	url := fmt.Sprintf("%s?username=%s", *server, *user)
-	log.Printf("Connecting to %s ...", url)
-	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	q := url.Values{"v": {strconv.Itoa(protocol.Version)}}
+	header := http.Header{}
+	switch {
+	case *resume != "":
+		q.Set("resume", *resume)
+	case *spectate != "":
+		q.Set("spectate", *spectate)
+	case *password != "":
+		token, err := login(*server, *user, *password, *register)
+		if err != nil {
+			log.Fatal("login: ", err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	default:
+		q.Set("guest", *user)
+	}
+	if *rows > 0 {
+		q.Set("rows", strconv.Itoa(*rows))
+	}
+	if *cols > 0 {
+		q.Set("cols", strconv.Itoa(*cols))
+	}
+	if *connect > 0 {
+		q.Set("connect", strconv.Itoa(*connect))
+	}
+	for key, val := range map[string]string{"difficulty": *difficulty, "engine": *engine, "room": *room, "time": *timeControl} {
+		if val != "" {
+			q.Set(key, val)
+		}
+	}
+	wsURL := *server + "?" + q.Encode()
+	var ext *game.ProcessEngine
+	if f := strings.Fields(*engineCmd); len(f) > 0 {
+		e, err := game.StartProcessEngine(f[0], f[1:]...)
+		if err != nil {
+			log.Fatal("engine:", err)
+		}
+		defer e.Close()
+		log.Printf("Using engine %s", e.Name)
+		ext = e
+		*auto = true
+	}
+
+	log.Printf("Connecting to %s ...", wsURL)
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
 	if err != nil {
+		if resp != nil {
+			// the server says why it refused, e.g. an expired login
+			msg, _ := io.ReadAll(resp.Body)
+			log.Fatalf("dial: %v: %s", err, strings.TrimSpace(string(msg)))
+		}
 		log.Fatal("dial:", err)
 	}
 	defer conn.Close()
 
 	var myColor = ""
+	var me = "" // our name as the server has it, e.g. guest-<name>
 	var board [][]*string
 	var nextTurn = "" // who moves next, "R" or "Y"
+	var numCols = 7   // updated from the start/rejoined payload
+	var connectN = 4
+	var clock *protocol.Clock // nil when untimed
 
 	// input reader for manual moves
 	reader := bufio.NewReader(os.Stdin)
 
-	// sender helper
+	// sender helpers; every message gets a seq so a rejection can be
+	// matched to what we sent. The stdin loop and the reader both send, and
+	// the socket takes one writer at a time, so writes happen under sentMu.
+	var sentMu sync.Mutex
+	var seq int
+	sent := map[int]string{} // seq -> what it was, for error messages
+	send := func(what string, msg func(protocol.Seq) protocol.Message) {
+		sentMu.Lock()
+		defer sentMu.Unlock()
+		seq++
+		sent[seq] = what
+		_ = conn.WriteJSON(msg(protocol.Seq{Seq: seq}))
+	}
 	sendMove := func(col int) {
-		payload := map[string]any{"type": "move", "col": col}
-		_ = conn.WriteJSON(payload)
+		send(fmt.Sprintf("move %d", col), func(s protocol.Seq) protocol.Message { return protocol.Move{Col: col, Seq: s} })
+	}
+
+	// auto-play pick: the external engine if there is one, else centre-first
+	autoCol := func() int {
+		if ext != nil {
+			col := -1
+			g, err := game.FromBoard(board, connectN)
+			if err == nil {
+				var left time.Duration
+				if clock != nil {
+					left = time.Duration(clock.R) * time.Millisecond
+					if myColor == "Y" {
+						left = time.Duration(clock.Y) * time.Millisecond
+					}
+				}
+				col, err = ext.ChooseMove(context.Background(), g, myColor, left)
+			}
+			if err == nil {
+				return col
+			}
+			// no telling what state it's in now; play on without it
+			log.Printf("engine: %v; dropping it", err)
+			ext.Close()
+			ext = nil
+		}
+		return firstPlayableCol(board)
+	}
+
+	commands := map[string]func(protocol.Seq) protocol.Message{
+		"resign":  func(s protocol.Seq) protocol.Message { return protocol.Resign{Seq: s} },
+		"draw":    func(s protocol.Seq) protocol.Message { return protocol.OfferDraw{Seq: s} },
+		"accept":  func(s protocol.Seq) protocol.Message { return protocol.AcceptDraw{Seq: s} },
+		"decline": func(s protocol.Seq) protocol.Message { return protocol.DeclineDraw{Seq: s} },
+		"rematch": func(s protocol.Seq) protocol.Message { return protocol.Rematch{Seq: s} },
+		"hint":    func(s protocol.Seq) protocol.Message { return protocol.Hint{Seq: s} },
 	}
 
 	// prompt loop (manual)
 	promptIfMyTurn := func() {
 		if myColor != "" && nextTurn == myColor && !*auto {
-			fmt.Print("Your move (enter column 0-6): ")
+			fmt.Printf("Your move (enter column 0-%d, or resign/draw/hint): ", numCols-1)
 		}
 	}
 
@@ -127,15 +255,20 @@ func main() {
 					promptIfMyTurn()
 					continue
 				}
+				// resign, draw, accept, decline, rematch, hint
+				if cmd, ok := commands[strings.ToLower(line)]; ok {
+					send(strings.ToLower(line), cmd)
+					continue
+				}
 				// only accept input when it's my turn
-				if nextTurn != myColor {
+				if myColor == "" || nextTurn != myColor {
 					fmt.Println("Not your turn yet.")
 					continue
 				}
 				var c int
 				_, err = fmt.Sscanf(line, "%d", &c)
-				if err != nil || c < 0 || c > 6 {
-					fmt.Println("Enter a valid column (0-6).")
+				if err != nil || c < 0 || c >= numCols {
+					fmt.Printf("Enter a valid column (0-%d).\n", numCols-1)
 					promptIfMyTurn()
 					continue
 				}
@@ -154,82 +287,130 @@ func main() {
 			return
 		}
 
-		// sniff type
-		var peek struct{ Type string `json:"type"` }
-		_ = json.Unmarshal(data, &peek)
+		msg, err := protocol.DecodeServer(data)
+		if err != nil {
+			fmt.Println("… unreadable message:", err)
+			continue
+		}
+
+		// start and rejoined look the same to us
+		intro, rejoined := "🎮 Game started!", false
+		if m, ok := msg.(*protocol.Rejoined); ok {
+			intro, rejoined = "🔁 Rejoined.", true
+			msg = (*protocol.Start)(m)
+		}
+
+		switch m := msg.(type) {
+		case *protocol.Hello:
+			// nothing to do, we only speak protocol.Version
+
+		case *protocol.Queued:
+			if m.Position > 0 {
+				fmt.Printf("⏳ %s (position %d of %d)\n", m.Message, m.Position, m.Waiting)
+			} else {
+				fmt.Println("⏳", m.Message)
+			}
 
-		switch peek.Type {
-		case "queued":
-			var m SimpleMsg
-			_ = json.Unmarshal(data, &m)
-			fmt.Println("⏳", m.Msg)
+		case *protocol.RoomCreated:
+			fmt.Printf("🔑 Room created. Share code %s (expires in %ds)\n", m.Code, m.ExpiresIn)
 
-		case "start":
-			var m StartMsg
-			_ = json.Unmarshal(data, &m)
+		case *protocol.Start:
 			myColor = m.Color
+			me = m.You
 			board = m.Board
 			nextTurn = m.Turn
-			fmt.Printf("🎮 Game started! You are %s vs %s. Next turn: %s\n", myColor, m.Opponent, nextTurn)
+			if m.Cols > 0 {
+				numCols, connectN = m.Cols, m.Connect
+			}
+			clock = m.Clock
+			fmt.Printf("%s You are %s vs %s. Next turn: %s\n", intro, myColor, m.Opponent, nextTurn)
+			if !m.Rated {
+				fmt.Println("(unrated)")
+			}
+			if m.Hints > 0 {
+				fmt.Printf("💡 %d hints available (type hint; the game won't be rated)\n", m.Hints)
+			}
+			if !rejoined {
+				fmt.Println("🔑 Lost connection? Rejoin with -resume", m.Resume)
+			}
 			printBoard(board)
+			if clock != nil {
+				fmt.Println(fmtClock(clock))
+			}
 			if *auto && nextTurn == myColor {
-				col := firstPlayableCol(board)
+				col := autoCol()
 				fmt.Printf("🤖 Auto move -> %d\n", col)
 				sendMove(col)
 			}
 			promptIfMyTurn()
 
-		case "rejoined":
-			var m StartMsg // same fields used
-			_ = json.Unmarshal(data, &m)
-			myColor = m.Color
+		case *protocol.Spectate:
 			board = m.Board
 			nextTurn = m.Turn
-			fmt.Printf("🔁 Rejoined. You are %s vs %s. Next turn: %s\n", myColor, m.Opponent, nextTurn)
+			clock = m.Clock
+			fmt.Printf("👀 Watching %s (R) vs %s (Y). Next turn: %s\n", m.Players.R, m.Players.Y, nextTurn)
 			printBoard(board)
-			if *auto && nextTurn == myColor {
-				col := firstPlayableCol(board)
-				fmt.Printf("🤖 Auto move -> %d\n", col)
-				sendMove(col)
+			if clock != nil {
+				fmt.Println(fmtClock(clock))
 			}
-			promptIfMyTurn()
 
-		case "update":
-			var m UpdateMsg
-			_ = json.Unmarshal(data, &m)
+		case *protocol.Update:
 			board = m.Board
 			nextTurn = m.Turn
+			clock = m.Clock
 			fmt.Printf("⬇️  %s played col %d (row %d). Next: %s\n", m.Move.Player, m.Move.Col, m.Move.Row, nextTurn)
 			printBoard(board)
+			if clock != nil {
+				fmt.Println(fmtClock(clock))
+			}
 			if *auto && nextTurn == myColor {
-				col := firstPlayableCol(board)
+				col := autoCol()
 				time.Sleep(300 * time.Millisecond)
 				fmt.Printf("🤖 Auto move -> %d\n", col)
 				sendMove(col)
 			}
 			promptIfMyTurn()
 
-		case "info":
-			var m SimpleMsg
-			_ = json.Unmarshal(data, &m)
-			fmt.Println("ℹ️ ", m.Msg)
+		case *protocol.Suggestion:
+			fmt.Printf("💡 Try column %d (%d hints left)\n", m.Col, m.HintsLeft)
+			promptIfMyTurn()
 
-		case "gameOver":
-			var m SimpleMsg
-			_ = json.Unmarshal(data, &m)
-			fmt.Println("🏁", m.Result)
-			printBoard(board)
-			return
+		case *protocol.Info:
+			fmt.Println("ℹ️ ", m.Message)
 
-		case "error":
-			var m SimpleMsg
-			_ = json.Unmarshal(data, &m)
-			fmt.Println("❌", m.Msg)
-			return
+		case *protocol.DrawOffered:
+			if m.By != me {
+				fmt.Printf("🤝 %s offers a draw (accept/decline)\n", m.By)
+			}
 
-		default:
-			fmt.Println("… unknown message:", string(data))
+		case *protocol.DrawDeclined:
+			if m.By != me {
+				fmt.Printf("🙅 %s declined the draw\n", m.By)
+			}
+
+		case *protocol.RematchOffered:
+			fmt.Printf("🔄 %s wants a rematch (type rematch)\n", m.By)
+
+		case *protocol.GameOver:
+			fmt.Printf("🏁 %s (%s)\n", m.Result, m.Reason)
+			printBoard(board)
+			if *auto || *spectate != "" {
+				return
+			}
+			myColor, nextTurn = "", ""
+			fmt.Println("Type rematch to play again, or Ctrl+C to quit.")
+
+		case *protocol.Error:
+			// fatal ones are followed by the server closing the socket
+			sentMu.Lock()
+			what, ok := sent[m.Seq]
+			sentMu.Unlock()
+			if ok {
+				fmt.Printf("❌ %s rejected: %s (%s)\n", what, m.Message, m.Code)
+				promptIfMyTurn()
+			} else {
+				fmt.Printf("❌ %s (%s)\n", m.Message, m.Code)
+			}
 		}
 	}
 }
-
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// runReplay is the replay subcommand: it plays a finished game back from
+// the server and reads pause/play/step/seek from stdin.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	server := fs.String("server", "ws://localhost:9090/ws", "WebSocket server URL")
+	speed := fs.Float64("speed", 1, "Playback speed, 2 = twice as fast as it was played")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli replay [-server url] [-speed n] <gameId>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	url := fmt.Sprintf("%s?v=%d&replay=%s&speed=%g", *server, protocol.Version, fs.Arg(0), *speed)
+	log.Printf("Connecting to %s ...", url)
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		if resp != nil {
+			msg, _ := io.ReadAll(resp.Body)
+			log.Fatalf("dial: %v: %s", err, strings.TrimSpace(string(msg)))
+		}
+		log.Fatal("dial:", err)
+	}
+	defer conn.Close()
+
+	var sendMu sync.Mutex
+	send := func(msg protocol.Message) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		_ = conn.WriteJSON(msg)
+	}
+
+	go func() {
+		in := bufio.NewScanner(os.Stdin)
+		for in.Scan() {
+			f := strings.Fields(strings.ToLower(in.Text()))
+			if len(f) == 0 {
+				f = []string{"step"}
+			}
+			switch {
+			case f[0] == "pause":
+				send(protocol.Pause{})
+			case f[0] == "play" && len(f) == 1:
+				send(protocol.Play{})
+			case f[0] == "play":
+				if v, err := strconv.ParseFloat(f[1], 64); err == nil {
+					send(protocol.Play{Speed: v})
+					continue
+				}
+				fmt.Println("usage: play [speed]")
+			case f[0] == "step":
+				send(protocol.Step{})
+			case f[0] == "seek" && len(f) == 2:
+				if n, err := strconv.Atoi(f[1]); err == nil {
+					send(protocol.Seek{Ply: n})
+					continue
+				}
+				fmt.Println("usage: seek <ply>")
+			default:
+				fmt.Println("commands: pause, play [speed], step (or Enter), seek <ply>")
+			}
+		}
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			log.Println("read:", err)
+			return
+		}
+		msg, err := protocol.DecodeServer(data)
+		if err != nil {
+			fmt.Println("… unreadable message:", err)
+			continue
+		}
+		switch m := msg.(type) {
+		case *protocol.Replay:
+			fmt.Printf("📼 Replaying %s (R) vs %s (Y), %d moves at %gx\n", m.Players.R, m.Players.Y, m.Moves, m.Speed)
+			fmt.Println("Type pause, play [speed], step (or Enter) or seek <ply>.")
+
+		case *protocol.Update:
+			fmt.Printf("⬇️  %s played col %d (row %d). Next: %s\n", m.Move.Player, m.Move.Col, m.Move.Row, m.Turn)
+			printBoard(m.Board)
+
+		case *protocol.ReplayState:
+			state := "paused"
+			if m.Playing {
+				state = fmt.Sprintf("playing at %gx", m.Speed)
+			}
+			fmt.Printf("📼 After %d moves, %s. Next: %s\n", m.Ply, state, m.Turn)
+			printBoard(m.Board)
+
+		case *protocol.GameOver:
+			fmt.Printf("🏁 %s (%s). Seek back to watch again, or Ctrl+C to quit.\n", m.Result, m.Reason)
+
+		case *protocol.Error:
+			fmt.Printf("❌ %s (%s)\n", m.Message, m.Code)
+		}
+	}
+}
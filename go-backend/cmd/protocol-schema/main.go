@@ -0,0 +1,32 @@
+// Command protocol-schema writes the WebSocket protocol's JSON Schema, for
+// the React client and anyone else writing one.
+//
+//	go run ./cmd/protocol-schema -o ../frontend/src/protocol/schema.json
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+func main() {
+	out := flag.String("o", "", "Output file (default stdout)")
+	flag.Parse()
+
+	b, err := protocol.Schema()
+	if err != nil {
+		log.Fatal(err)
+	}
+	b = append(b, '\n')
+	if *out == "" {
+		_, err = os.Stdout.Write(b)
+	} else {
+		err = os.WriteFile(*out, b, 0o644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/yourname/fourinarow/internal/api"
+	"github.com/yourname/fourinarow/internal/auth"
 	"github.com/yourname/fourinarow/internal/config"
 	"github.com/yourname/fourinarow/internal/game"
 	"github.com/yourname/fourinarow/internal/store"
@@ -15,16 +21,50 @@ import (
 func main() {
 	cfg := config.Load()
 
-	// Connect to Mongo (10s timeout)
+	// Connect to the store (10s timeout)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	mongoStore, err := store.NewMongoStore(ctx, cfg.MongoURI)
+	var db store.Store
+	var err error
+	switch cfg.Store {
+	case "mongo":
+		db, err = store.NewMongoStore(ctx, cfg.MongoURI)
+	case "bolt":
+		db, err = store.NewBoltStore(cfg.BoltPath)
+	case "memory":
+		db = store.NewMemoryStore()
+	default:
+		log.Fatalf("unknown STORE %q (want mongo, bolt or memory)", cfg.Store)
+	}
 	if err != nil {
-		log.Fatalf("mongo connect error: %v", err)
+		log.Fatalf("%s store error: %v", cfg.Store, err)
+	}
+	log.Printf("using %s store", cfg.Store)
+
+	for name, cmd := range cfg.ExternalEngines {
+		game.RegisterProcessEngine(name, cmd)
+		log.Printf("registered external engine %q: %s", name, cmd)
+	}
+
+	mgr := game.NewManager(db, cfg.MatchBotAfterMs, cfg.RejoinGraceMs, cfg.BotMoveDelayMs, cfg.RoomExpiryMs, cfg.MatchRatingWindow)
+	mgr.BotRating = float64(cfg.BotRating)
+	mgr.AnnotateMove = time.Duration(cfg.AnnotateMoveMs) * time.Millisecond
+	mgr.HintsPerGame = cfg.HintsPerGame
+	if cfg.TokenSecret != "" {
+		mgr.TokenSecret = []byte(cfg.TokenSecret)
+	} else {
+		log.Printf("TOKEN_SECRET not set; logins and resume tokens won't survive a restart, so nobody can get back into a restored game")
 	}
+	sessions := auth.Tokens{Secret: mgr.TokenSecret, TTL: time.Duration(cfg.SessionTTLHours) * time.Hour}
+	mgr.Sessions = sessions
 
-	mgr := game.NewManager(mongoStore, cfg.MatchBotAfterMs, cfg.RejoinGraceMs, cfg.BotMoveDelayMs)
+	// pick up the games that were in progress when we last stopped
+	if n, err := mgr.Restore(ctx); err != nil {
+		log.Printf("restore games: %v", err)
+	} else if n > 0 {
+		log.Printf("restored %d game(s) in progress", n)
+	}
 
 	mux := http.NewServeMux()
 
@@ -36,7 +76,7 @@ func main() {
 
 	// Leaderboard (log real error so we can diagnose 500s)
 	mux.HandleFunc("/leaderboard", func(w http.ResponseWriter, r *http.Request) {
-		top, err := mongoStore.TopPlayers(r.Context(), 10)
+		top, err := db.TopPlayers(r.Context(), 10, cfg.LeaderboardMin)
 		if err != nil {
 			log.Printf("leaderboard error: %v", err) // <— view this in server console
 			http.Error(w, "db error", http.StatusInternalServerError)
@@ -46,6 +86,35 @@ func main() {
 		_ = json.NewEncoder(w).Encode(top)
 	})
 
+	// Accounts; both answer with a token for /ws?token=
+	accounts := &auth.Handler{Store: db, Tokens: sessions}
+	mux.HandleFunc("/auth/register", accounts.Register)
+	mux.HandleFunc("/auth/login", accounts.Login)
+
+	// Engines that can be picked with /ws?engine=<name>
+	mux.HandleFunc("/engines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(game.EngineNames())
+	})
+
+	// Games in progress, watch one with /ws?spectate=<gameId>
+	mux.HandleFunc("GET /games/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(mgr.LiveGames())
+	})
+
+	// Finished games and player profiles
+	history := &api.Handler{Store: db}
+	mux.HandleFunc("GET /games/{id}", history.Game) // or {id}.c4n for the record
+	mux.HandleFunc("POST /games/import", history.Import)
+	mux.HandleFunc("GET /players/{name}", history.Player)
+	mux.HandleFunc("GET /players/{name}/games", history.PlayerGames)
+	mux.HandleFunc("GET /players/{a}/vs/{b}", history.HeadToHead)
+
+	// "What should I have played?"; time-limited searches, a couple at a time
+	analyzer := api.NewAnalyzer(time.Duration(cfg.AnalyzeMaxMs)*time.Millisecond, cfg.AnalyzeConcurrent)
+	mux.HandleFunc("POST /analyze", analyzer.Analyze)
+
 	// WebSocket
 	mux.HandleFunc("/ws", mgr.HandleWS)
 
@@ -57,8 +126,30 @@ func main() {
 		Handler: handler,
 	}
 
-	log.Printf("🚀 Go backend on http://localhost:%s", cfg.Port)
-	log.Fatal(server.ListenAndServe())
+	go func() {
+		log.Printf("🚀 Go backend on http://localhost:%s", cfg.Port)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Graceful shutdown: stop accepting connections, checkpoint the games in
+	// progress so the next start picks them up, then close the store.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	log.Printf("shutting down...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	if err := mgr.Shutdown(shutdownCtx); err != nil {
+		log.Printf("game shutdown: %v", err)
+	}
+	if err := db.Close(shutdownCtx); err != nil {
+		log.Printf("close %s store: %v", cfg.Store, err)
+	}
 }
 
 // very small CORS middleware (adjust origin if you want)
@@ -4,7 +4,9 @@ go 1.22
 
 require (
 	github.com/gorilla/websocket v1.5.1
+	go.etcd.io/bbolt v1.3.10
 	go.mongodb.org/mongo-driver v1.16.0
+	golang.org/x/crypto v0.22.0
 )
 
 require (
@@ -15,8 +17,8 @@ require (
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
-	golang.org/x/crypto v0.22.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
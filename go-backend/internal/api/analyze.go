@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/game"
+)
+
+const maxAnalyzeBody = 16 << 10
+
+// Analyzer serves POST /analyze. Searches are all CPU, so each request gets
+// at most MaxTime and only a few run at once; the rest are turned away with
+// 503 rather than queued.
+type Analyzer struct {
+	MaxTime time.Duration
+	slots   chan struct{}
+}
+
+func NewAnalyzer(maxTime time.Duration, concurrent int) *Analyzer {
+	return &Analyzer{MaxTime: maxTime, slots: make(chan struct{}, max(concurrent, 1))}
+}
+
+// analyzeRequest is the position to look at, either as the moves that led
+// to it or as the board itself.
+type analyzeRequest struct {
+	Moves   []int       `json:"moves"` // 0-based columns from the empty board, R first
+	Board   [][]*string `json:"board"` // top row first, like every other board
+	Rows    int         `json:"rows"`  // with moves; Standard if left out
+	Cols    int         `json:"cols"`
+	Connect int         `json:"connect"`
+	TimeMs  int         `json:"timeMs"` // up to MaxTime, which is also the default
+}
+
+// Analyze answers with a game.Analysis of the position.
+func (a *Analyzer) Analyze(w http.ResponseWriter, r *http.Request) {
+	var req analyzeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnalyzeBody)).Decode(&req); err != nil {
+		http.Error(w, "bad request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	g, err := req.position()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	budget := a.MaxTime
+	if t := time.Duration(req.TimeMs) * time.Millisecond; t > 0 && t < budget {
+		budget = t
+	}
+
+	select {
+	case a.slots <- struct{}{}:
+		defer func() { <-a.slots }()
+	default:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many analyses running, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, game.Analyze(r.Context(), g, budget))
+}
+
+func (req analyzeRequest) position() (*game.GameLogic, error) {
+	var g *game.GameLogic
+	var err error
+	if req.Board != nil {
+		connect := req.Connect
+		if connect == 0 {
+			connect = game.Standard.Connect
+		}
+		g, err = game.FromBoard(req.Board, connect)
+	} else {
+		v := game.Standard
+		if req.Rows > 0 {
+			v.Rows = req.Rows
+		}
+		if req.Cols > 0 {
+			v.Cols = req.Cols
+		}
+		if req.Connect > 0 {
+			v.Connect = req.Connect
+		}
+		g, err = game.PlayMoves(v, req.Moves)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g, g.Playable()
+}
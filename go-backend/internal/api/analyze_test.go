@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/game"
+)
+
+func analyze(a *Analyzer, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.Analyze(rec, httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(body)))
+	return rec
+}
+
+func TestAnalyze(t *testing.T) {
+	a := NewAnalyzer(time.Second, 2)
+	for _, body := range []string{
+		`{"moves":[0,1,0,1,0,1]}`,
+		`{"board":[[null,null,null,null,null,null,null],[null,null,null,null,null,null,null],[null,null,null,null,null,null,null],["R","Y",null,null,null,null,null],["R","Y",null,null,null,null,null],["R","Y",null,null,null,null,null]],"timeMs":500}`,
+	} {
+		rec := analyze(a, body)
+		var got game.Analysis
+		if err := json.NewDecoder(rec.Body).Decode(&got); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("%s: HTTP %d, %v", body, rec.Code, err)
+		}
+		if got.ToMove != "R" || got.BestMove != 0 || got.Outcome != game.OutcomeWin || got.Plies != 1 {
+			t.Errorf("%s: %+v", body, got)
+		}
+	}
+}
+
+func TestAnalyzeRejects(t *testing.T) {
+	a := NewAnalyzer(time.Second, 1)
+	for _, body := range []string{
+		`not json`,
+		`{"moves":[7]}`,
+		`{"moves":[0,1,0,1,0,1,0]}`, // already won
+		`{"moves":[0],"rows":2,"cols":2,"connect":3}`,
+		`{"board":[["R","R"]]}`,
+	} {
+		if rec := analyze(a, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: HTTP %d, want 400", body, rec.Code)
+		}
+	}
+
+	// every slot taken: turned away, not queued
+	a.slots <- struct{}{}
+	rec := analyze(a, `{"moves":[3]}`)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("busy: HTTP %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,219 @@
+// Package api is the read-only REST side of the server: finished games and
+// player profiles, straight from the store, and position analysis.
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/game"
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+const (
+	defaultPage = 20
+	maxPage     = 100
+	maxImport   = 64 << 10 // far more than the longest game on the biggest board
+)
+
+// Handler serves
+//
+//	GET /games/{id}
+//	GET /games/{id}.c4n
+//	POST /games/import
+//	GET /players/{name}
+//	GET /players/{name}/games?opponent=&result=&from=&to=&bot=&limit=&cursor=
+//	GET /players/{a}/vs/{b}
+type Handler struct {
+	Store store.Store
+}
+
+// Game is a stored game as JSON, or as c4n with ".c4n" on the id.
+func (h *Handler) Game(w http.ResponseWriter, r *http.Request) {
+	id, c4n := strings.CutSuffix(r.PathValue("id"), ".c4n")
+	g, err := h.Store.GetGame(r.Context(), id)
+	if err != nil {
+		storeError(w, "game", err)
+		return
+	}
+	if !c4n {
+		writeJSON(w, g)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+id+`.c4n"`)
+	_, _ = io.WriteString(w, game.RecordFromDoc(g).String())
+}
+
+// Import checks a c4n game played elsewhere and answers with it in the
+// same shape as GET /games/{id}. Nothing is stored.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImport))
+	if err != nil {
+		http.Error(w, "game record too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	rec, err := game.ParseRecord(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	g, err := rec.Doc()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, g)
+}
+
+// Profile is a player as GET /players/{name} serves it: the stored record
+// plus their accuracy over the moves the annotator has rated.
+type Profile struct {
+	models.Player
+	Accuracy *float64 `json:"accuracy"` // percent; null until a game has been annotated
+}
+
+func (h *Handler) Player(w http.ResponseWriter, r *http.Request) {
+	p, err := h.Store.GetPlayer(r.Context(), r.PathValue("name"))
+	if err != nil {
+		storeError(w, "player", err)
+		return
+	}
+	prof := Profile{Player: p}
+	if p.Annotated.Total() > 0 {
+		acc := p.Annotated.Accuracy()
+		prof.Accuracy = &acc
+	}
+	writeJSON(w, prof)
+}
+
+// GamePage is one page of a player's games, newest first. Next is the
+// cursor for the page after it, empty on the last one.
+type GamePage struct {
+	Games []models.GameDoc `json:"games"`
+	Next  string           `json:"next,omitempty"`
+}
+
+func (h *Handler) PlayerGames(w http.ResponseWriter, r *http.Request) {
+	q, err := gameQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := q.Limit
+	q.Limit++ // one extra to tell whether there's another page
+	games, err := h.Store.PlayerGames(r.Context(), r.PathValue("name"), q)
+	if err != nil {
+		storeError(w, "games", err)
+		return
+	}
+	page := GamePage{Games: games}
+	if len(games) > limit {
+		page.Games = games[:limit]
+		page.Next = encodeCursor(store.CursorOf(games[limit-1]))
+	}
+	if page.Games == nil {
+		page.Games = []models.GameDoc{}
+	}
+	writeJSON(w, page)
+}
+
+func (h *Handler) HeadToHead(w http.ResponseWriter, r *http.Request) {
+	hh, err := h.Store.HeadToHead(r.Context(), r.PathValue("a"), r.PathValue("b"))
+	if err != nil {
+		storeError(w, "head to head", err)
+		return
+	}
+	writeJSON(w, hh)
+}
+
+// gameQuery reads the filters and page position off r.
+func gameQuery(r *http.Request) (store.GameQuery, error) {
+	v := r.URL.Query()
+	q := store.GameQuery{Opponent: v.Get("opponent"), Result: v.Get("result"), Limit: defaultPage}
+	switch q.Result {
+	case "", "win", "loss", "draw":
+	default:
+		return q, fmt.Errorf("result must be win, loss or draw")
+	}
+	var err error
+	if q.From, err = parseDate(v.Get("from")); err != nil {
+		return q, err
+	}
+	if q.To, err = parseDate(v.Get("to")); err != nil {
+		return q, err
+	}
+	if s := v.Get("bot"); s != "" {
+		bot, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, fmt.Errorf("bot must be true or false")
+		}
+		q.VsBot = &bot
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 1 || q.Limit > maxPage {
+			return q, fmt.Errorf("limit must be 1 to %d", maxPage)
+		}
+	}
+	if s := v.Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return q, err
+		}
+		q.Before = &c
+	}
+	return q, nil
+}
+
+// parseDate takes RFC 3339 or a plain 2006-01-02 (midnight UTC).
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q, want 2006-01-02 or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// Cursors are opaque to clients: "<unix nanos>.<gameId>", base64url'd.
+func encodeCursor(c store.GameCursor) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d.%s", c.CreatedAt.UnixNano(), c.GameID))
+}
+
+func decodeCursor(s string) (store.GameCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	nanos, id, ok := strings.Cut(string(b), ".")
+	n, perr := strconv.ParseInt(nanos, 10, 64)
+	if err != nil || !ok || perr != nil {
+		return store.GameCursor{}, errors.New("invalid cursor")
+	}
+	return store.GameCursor{CreatedAt: time.Unix(0, n), GameID: id}, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func storeError(w http.ResponseWriter, what string, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, what+" not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("%s: %v", what, err)
+	http.Error(w, "db error", http.StatusInternalServerError)
+}
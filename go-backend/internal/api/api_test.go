@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// serve puts h behind the routes the server uses; get fetches path and
+// decodes the body into v, if there is one, returning the status.
+func serve(t *testing.T, h *Handler) (get func(path string, v any) int) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /games/{id}", h.Game)
+	mux.HandleFunc("GET /players/{name}", h.Player)
+	mux.HandleFunc("GET /players/{name}/games", h.PlayerGames)
+	mux.HandleFunc("GET /players/{a}/vs/{b}", h.HeadToHead)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return func(path string, v any) int {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+}
+
+// fiveGames stores five games alice won against bob, g0 the oldest.
+func fiveGames(t *testing.T) store.Store {
+	t.Helper()
+	s := store.NewMemoryStore()
+	ctx := context.Background()
+	for _, name := range []string{"alice", "bob"} {
+		if err := s.EnsurePlayer(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		g := models.GameDoc{GameID: fmt.Sprintf("g%d", i), Player1: "alice", Player2: "bob", Winner: "alice", Reason: "connect"}
+		if err := s.InsertGame(ctx, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestGameAndPlayer(t *testing.T) {
+	h := &Handler{Store: fiveGames(t)}
+	get := serve(t, h)
+	var g models.GameDoc
+	if code := get("/games/g1", &g); code != http.StatusOK || g.Winner != "alice" {
+		t.Errorf("GET /games/g1: %d %+v", code, g)
+	}
+	var p Profile
+	if code := get("/players/alice", &p); code != http.StatusOK || p.Username != "alice" || p.Accuracy != nil {
+		t.Errorf("GET /players/alice: %d %+v", code, p)
+	}
+	// alice is R, so hers are the first and third
+	moves := []models.Move{{Player: "R", Col: 3}, {Player: "Y", Col: 3}, {Player: "R", Col: 2}}
+	notes := []models.MoveNote{{Class: models.NoteBest}, {Class: models.NoteBlunder}, {Class: models.NoteInaccuracy}}
+	if err := h.Store.InsertGame(context.Background(), models.GameDoc{GameID: "noted", Player1: "alice", Player2: "carol", Moves: moves}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Store.AnnotateGame(context.Background(), "noted", notes); err != nil {
+		t.Fatal(err)
+	}
+	p = Profile{}
+	if get("/players/alice", &p); p.Accuracy == nil || *p.Accuracy != 75 {
+		t.Errorf("accuracy after an annotated game: %+v", p)
+	}
+	var hh models.HeadToHead
+	if code := get("/players/alice/vs/bob", &hh); code != http.StatusOK || hh.Games != 5 || hh.WinsA != 5 {
+		t.Errorf("GET /players/alice/vs/bob: %d %+v", code, hh)
+	}
+	for _, path := range []string{"/games/nope", "/players/nobody"} {
+		if code := get(path, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s: %d, want 404", path, code)
+		}
+	}
+}
+
+func TestPlayerGamesPages(t *testing.T) {
+	get := serve(t, &Handler{Store: fiveGames(t)})
+	var ids []string
+	path := "/players/alice/games?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatalf("still paging after %v", ids)
+		}
+		var page GamePage
+		if code := get(path, &page); code != http.StatusOK {
+			t.Fatalf("GET %s: %d", path, code)
+		}
+		for _, g := range page.Games {
+			ids = append(ids, g.GameID)
+		}
+		path = ""
+		if page.Next != "" {
+			path = "/players/alice/games?limit=2&cursor=" + page.Next
+		}
+	}
+	if want := []string{"g4", "g3", "g2", "g1", "g0"}; !slices.Equal(ids, want) {
+		t.Errorf("paged through %v, want %v", ids, want)
+	}
+
+	// no matches is an empty list, not null
+	var page GamePage
+	if code := get("/players/alice/games?result=loss", &page); code != http.StatusOK || page.Games == nil || len(page.Games) != 0 || page.Next != "" {
+		t.Errorf("losses: %d %+v", code, page)
+	}
+	if code := get("/players/alice/games?from=2020-01-01&to=2999-01-01T00:00:00Z&bot=false&opponent=bob", &page); code != http.StatusOK || len(page.Games) != 5 {
+		t.Errorf("all filters: %d, %d games", code, len(page.Games))
+	}
+	for _, bad := range []string{"result=lost", "limit=0", "limit=101", "cursor=!!", "from=yesterday", "bot=maybe"} {
+		if code := get("/players/alice/games?"+bad, nil); code != http.StatusBadRequest {
+			t.Errorf("?%s: %d, want 400", bad, code)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := store.GameCursor{CreatedAt: time.Date(2024, 5, 1, 18, 4, 5, 123456789, time.UTC), GameID: "k3J9.xQ"}
+	got, err := decodeCursor(encodeCursor(c))
+	if err != nil || !got.CreatedAt.Equal(c.CreatedAt) || got.GameID != c.GameID {
+		t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v, %v", c, got, err)
+	}
+}
+
+func TestExportAndImport(t *testing.T) {
+	s := store.NewMemoryStore()
+	var moves []models.Move
+	for i, col := range []int{0, 1, 0, 1, 0, 1, 0} {
+		p := "R"
+		if i%2 == 1 {
+			p = "Y"
+		}
+		moves = append(moves, models.Move{Player: p, Col: col, Row: 5 - i/2})
+	}
+	stored := models.GameDoc{GameID: "g1", Player1: "alice", Player2: "bob", Winner: "alice", Reason: "connect", Moves: moves, Rows: 6, Cols: 7, Connect: 4}
+	if err := s.InsertGame(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Store: s}
+
+	req := httptest.NewRequest(http.MethodGet, "/games/g1.c4n", nil)
+	req.SetPathValue("id", "g1.c4n")
+	rec := httptest.NewRecorder()
+	h.Game(rec, req)
+	c4n := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") || !strings.Contains(c4n, "\n1212121\n") {
+		t.Fatalf("export: %d %s\n%s", rec.Code, rec.Header(), c4n)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Import(rec, httptest.NewRequest(http.MethodPost, "/games/import", strings.NewReader(c4n)))
+	var g models.GameDoc
+	if err := json.NewDecoder(rec.Body).Decode(&g); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("import: %d %v", rec.Code, err)
+	}
+	if g.GameID != "g1" || g.Winner != "alice" || g.Reason != "connect" || !reflect.DeepEqual(g.Moves, moves) {
+		t.Errorf("imported %+v", g)
+	}
+
+	rec = httptest.NewRecorder()
+	h.Import(rec, httptest.NewRequest(http.MethodPost, "/games/import", strings.NewReader("1111111")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("importing a full column: %d", rec.Code)
+	}
+}
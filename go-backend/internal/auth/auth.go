@@ -0,0 +1,116 @@
+// Package auth is accounts and sessions: bcrypt password hashes, the rules
+// for usernames, and the signed bearer tokens handed out at login.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GuestPrefix marks a guest's username. Nobody can register a name starting
+// with it, so guests never collide with accounts, and anything holding just
+// a username (a stored game, a checkpoint) can tell a guest apart.
+const GuestPrefix = "guest-"
+
+func IsGuest(username string) bool { return strings.HasPrefix(username, GuestPrefix) }
+
+const (
+	minPassword = 8
+	maxPassword = 72 // bcrypt ignores anything past this
+)
+
+var (
+	ErrBadToken     = errors.New("invalid or expired token")
+	ErrBadLogin     = errors.New("wrong username or password")
+	errReservedName = errors.New("that username is reserved")
+)
+
+// ValidUsername checks a name someone wants to register or play as a guest
+// under: 3 to 20 letters, digits, '_' or '-'.
+func ValidUsername(name string) error {
+	if len(name) < 3 || len(name) > 20 {
+		return fmt.Errorf("username must be 3 to 20 characters")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("username may only use letters, digits, '_' and '-'")
+		}
+	}
+	return nil
+}
+
+// HashPassword bcrypts pw after checking its length.
+func HashPassword(pw string) (string, error) {
+	if len(pw) < minPassword || len(pw) > maxPassword {
+		return "", fmt.Errorf("password must be %d to %d characters", minPassword, maxPassword)
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(h), nil
+}
+
+func CheckPassword(hash, pw string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw)) == nil
+}
+
+// DefaultTTL is how long a session lasts unless configured otherwise.
+const DefaultTTL = 30 * 24 * time.Hour
+
+// Tokens issues and checks session tokens: "<username>.<expiry>." plus an
+// HMAC of that under Secret. There's nothing stored server side, so a token
+// is good until it expires.
+type Tokens struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+// Issue gives username a token that expires TTL from now.
+func (t Tokens) Issue(username string) (token string, expires time.Time) {
+	expires = time.Now().Add(t.TTL).Truncate(time.Second)
+	payload := username + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + t.sign(payload), expires
+}
+
+// Verify returns the username token was issued to.
+func (t Tokens) Verify(token string) (string, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", ErrBadToken
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(t.sign(payload))) {
+		return "", ErrBadToken
+	}
+	username, exp, _ := strings.Cut(payload, ".")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return "", ErrBadToken
+	}
+	return username, nil
+}
+
+func (t Tokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.Secret)
+	mac.Write([]byte("session:" + payload)) // the secret also signs resume tokens
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// BearerToken is the session token on r: the Authorization header, or
+// ?token= for browsers, which can't set headers on a WebSocket.
+func BearerToken(r *http.Request) string {
+	if h, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(h)
+	}
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidUsername(t *testing.T) {
+	for name, ok := range map[string]bool{
+		"alice":                 true,
+		"Bob_the-2nd":           true,
+		"abc":                   true,
+		"twenty-characters-ok":  true,
+		"ab":                    false,
+		"twenty-one-characters": false,
+		"has space":             false,
+		"dot.ted":               false,
+		"émile":                 false,
+		"":                      false,
+	} {
+		if err := ValidUsername(name); (err == nil) != ok {
+			t.Errorf("ValidUsername(%q) = %v, want ok %v", name, err, ok)
+		}
+	}
+}
+
+func TestPasswords(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword(hash, "correct horse") || CheckPassword(hash, "correct horsE") {
+		t.Error("CheckPassword doesn't tell the password apart")
+	}
+	for _, pw := range []string{"short", strings.Repeat("x", 73)} {
+		if _, err := HashPassword(pw); err == nil {
+			t.Errorf("hashed a %d character password", len(pw))
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tokens := Tokens{Secret: []byte("secret"), TTL: time.Hour}
+	token, expires := tokens.Issue("alice")
+	if d := time.Until(expires); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expires in %v", d)
+	}
+	if name, err := tokens.Verify(token); err != nil || name != "alice" {
+		t.Fatalf("Verify = %q, %v", name, err)
+	}
+
+	payload, _, _ := strings.Cut(token, ".")
+	forged := strings.Replace(token, payload, "mallory", 1)
+	expired, _ := Tokens{Secret: []byte("secret"), TTL: -time.Hour}.Issue("alice")
+	other, _ := Tokens{Secret: []byte("other"), TTL: time.Hour}.Issue("alice")
+	for name, bad := range map[string]string{
+		"empty":        "",
+		"no signature": "alice.99999999999",
+		"forged":       forged,
+		"expired":      expired,
+		"other secret": other,
+	} {
+		if _, err := tokens.Verify(bad); err != ErrBadToken {
+			t.Errorf("%s: Verify = %v, want ErrBadToken", name, err)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest("GET", "/ws?token=from-query", nil)
+	if got := BearerToken(r); got != "from-query" {
+		t.Errorf("query: %q", got)
+	}
+	r.Header.Set("Authorization", "Bearer from-header")
+	if got := BearerToken(r); got != "from-header" {
+		t.Errorf("header: %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// Handler serves POST /auth/register and POST /auth/login. Both take
+// {"username":..,"password":..} and answer with a Session.
+type Handler struct {
+	Store  store.Store
+	Tokens Tokens
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Session is a logged-in username's bearer token.
+type Session struct {
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	c, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+	if err := ValidUsername(c.Username); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if IsGuest(c.Username) || strings.EqualFold(c.Username, store.BotName) {
+		http.Error(w, errReservedName.Error(), http.StatusBadRequest)
+		return
+	}
+	// a name with games on its record from before accounts existed can't
+	// be claimed: nothing shows the registrant is who played them
+	p, err := h.Store.GetPlayer(r.Context(), c.Username)
+	switch {
+	case err == nil && p.RatedGames+p.Wins+p.Losses+p.Draws > 0:
+		http.Error(w, "that username already has a record", http.StatusConflict)
+		return
+	case err != nil && !errors.Is(err, store.ErrNotFound):
+		log.Printf("register %s: %v", c.Username, err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	hash, err := HashPassword(c.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = h.Store.CreateAccount(r.Context(), models.Account{Username: c.Username, PasswordHash: hash})
+	if errors.Is(err, store.ErrTaken) {
+		http.Error(w, "that username is taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("register %s: %v", c.Username, err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	_ = h.Store.EnsurePlayer(r.Context(), c.Username)
+	h.startSession(w, c.Username)
+}
+
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	c, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+	a, err := h.Store.GetAccount(r.Context(), c.Username)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("login %s: %v", c.Username, err)
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	if err != nil || !CheckPassword(a.PasswordHash, c.Password) {
+		http.Error(w, ErrBadLogin.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.startSession(w, a.Username)
+}
+
+func readCredentials(w http.ResponseWriter, r *http.Request) (credentials, bool) {
+	var c credentials
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return c, false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&c); err != nil {
+		http.Error(w, "want {\"username\":..,\"password\":..}", http.StatusBadRequest)
+		return c, false
+	}
+	return c, true
+}
+
+func (h *Handler) startSession(w http.ResponseWriter, username string) {
+	token, expires := h.Tokens.Issue(username)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Session{Username: username, Token: token, ExpiresAt: expires})
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// post sends body to handler and returns the response.
+func post(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(body)))
+	return rec
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	h := &Handler{Store: store.NewMemoryStore(), Tokens: Tokens{Secret: []byte("secret"), TTL: time.Hour}}
+	// carol played before accounts; dave's record is there but empty
+	ctx := context.Background()
+	for _, name := range []string{"carol", "dave", "erin"} {
+		if err := h.Store.EnsurePlayer(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := h.Store.RateGame(ctx, "carol", "erin", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := post(h.Register, `{"username":"alice","password":"correct horse"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("register: HTTP %d %s", rec.Code, rec.Body)
+	}
+	var s Session
+	if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if name, err := h.Tokens.Verify(s.Token); err != nil || name != "alice" || s.Username != "alice" {
+		t.Errorf("session %+v: %q, %v", s, name, err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		code    int
+	}{
+		{"taken", h.Register, `{"username":"alice","password":"another one"}`, http.StatusConflict},
+		{"a rated record", h.Register, `{"username":"carol","password":"correct horse"}`, http.StatusConflict},
+		{"an empty record", h.Register, `{"username":"dave","password":"correct horse"}`, http.StatusOK},
+		{"guest name", h.Register, `{"username":"guest-bob","password":"correct horse"}`, http.StatusBadRequest},
+		{"bot name", h.Register, `{"username":"bot","password":"correct horse"}`, http.StatusBadRequest},
+		{"bad name", h.Register, `{"username":"a b","password":"correct horse"}`, http.StatusBadRequest},
+		{"short password", h.Register, `{"username":"bob","password":"short"}`, http.StatusBadRequest},
+		{"not json", h.Register, `username=bob`, http.StatusBadRequest},
+		{"login", h.Login, `{"username":"alice","password":"correct horse"}`, http.StatusOK},
+		{"wrong password", h.Login, `{"username":"alice","password":"correct horsE"}`, http.StatusUnauthorized},
+		{"nobody", h.Login, `{"username":"bob","password":"correct horse"}`, http.StatusUnauthorized},
+	} {
+		if rec := post(tt.handler, tt.body); rec.Code != tt.code {
+			t.Errorf("%s: HTTP %d %s, want %d", tt.name, rec.Code, strings.TrimSpace(rec.Body.String()), tt.code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: HTTP %d", rec.Code)
+	}
+}
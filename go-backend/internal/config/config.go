@@ -3,14 +3,28 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Port             string
-	MongoURI         string
-	MatchBotAfterMs  int
-	RejoinGraceMs    int
-	BotMoveDelayMs   int
+	Port              string
+	MongoURI          string
+	Store             string // "mongo", "bolt" or "memory"
+	BoltPath          string
+	MatchBotAfterMs   int
+	RejoinGraceMs     int
+	BotMoveDelayMs    int
+	RoomExpiryMs      int
+	MatchRatingWindow int               // 0 pairs strictly first come, first served
+	ExternalEngines   map[string]string // engine name -> command line
+	BotRating         int               // 0 keeps bot games out of ratings
+	LeaderboardMin    int               // rated games needed to appear on the leaderboard
+	TokenSecret       string            // signs resume and session tokens; unset means a random one per run
+	SessionTTLHours   int               // how long a login lasts
+	AnalyzeMaxMs      int               // longest a POST /analyze may search
+	AnalyzeConcurrent int               // analyses allowed at once; more get 503
+	AnnotateMoveMs    int               // engine time per position when annotating finished games; 0 = off
+	HintsPerGame      int               // hints each player may ask for per game; 0 = off
 }
 
 func getenv(key, def string) string {
@@ -29,12 +43,37 @@ func geti(key string, def int) int {
 	return def
 }
 
+// getengines parses "name=cmd args;other=cmd" into name -> command.
+func getengines(key string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(os.Getenv(key), ";") {
+		name, cmd, ok := strings.Cut(part, "=")
+		if ok && strings.TrimSpace(name) != "" && strings.TrimSpace(cmd) != "" {
+			out[strings.TrimSpace(name)] = strings.TrimSpace(cmd)
+		}
+	}
+	return out
+}
+
 func Load() Config {
 	return Config{
-		Port:            getenv("PORT", "9090"),
-		MongoURI:        getenv("MONGO_URI", "mongodb://localhost:27017"),
-		MatchBotAfterMs: geti("MATCH_BOT_AFTER_MS", 10000),
-		RejoinGraceMs:   geti("REJOIN_GRACE_MS", 30000),
-		BotMoveDelayMs:  geti("BOT_MOVE_DELAY_MS", 400),
+		Port:              getenv("PORT", "9090"),
+		MongoURI:          getenv("MONGO_URI", "mongodb://localhost:27017"),
+		Store:             getenv("STORE", "mongo"),
+		BoltPath:          getenv("BOLT_PATH", "fourinarow.db"),
+		MatchBotAfterMs:   geti("MATCH_BOT_AFTER_MS", 10000),
+		RejoinGraceMs:     geti("REJOIN_GRACE_MS", 30000),
+		BotMoveDelayMs:    geti("BOT_MOVE_DELAY_MS", 400),
+		RoomExpiryMs:      geti("ROOM_EXPIRY_MS", 600000),
+		MatchRatingWindow: geti("MATCH_RATING_WINDOW", 0),
+		ExternalEngines:   getengines("EXTERNAL_ENGINES"),
+		BotRating:         geti("BOT_RATING", 0),
+		LeaderboardMin:    geti("LEADERBOARD_MIN_GAMES", 5),
+		TokenSecret:       os.Getenv("TOKEN_SECRET"),
+		SessionTTLHours:   geti("SESSION_TTL_HOURS", 720),
+		AnalyzeMaxMs:      geti("ANALYZE_MAX_MS", 3000),
+		AnalyzeConcurrent: geti("ANALYZE_CONCURRENCY", 2),
+		AnnotateMoveMs:    geti("ANNOTATE_MOVE_MS", 200),
+		HintsPerGame:      geti("HINTS_PER_GAME", 3),
 	}
 }
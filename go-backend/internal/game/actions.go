@@ -0,0 +1,122 @@
+package game
+
+import (
+	"io"
+	"log"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// Resigning, draw offers and rematches. Each runs on st's goroutine and
+// returns the rejection to send back when the action can't be done, nil
+// otherwise.
+
+func (m *Manager) resign(st *state, side string) *protocol.Error {
+	if st.over {
+		return reject(protocol.CodeGameOver, "the game is already over")
+	}
+	m.endGame(st, st.seat(opponent(side)).username, "resign")
+	return nil
+}
+
+func (m *Manager) offerDraw(st *state, side string) *protocol.Error {
+	switch {
+	case st.over:
+		return reject(protocol.CodeGameOver, "the game is already over")
+	case st.drawOffer == opponent(side):
+		// both want it
+		m.endGame(st, "Draw", "drawAgreed")
+		return nil
+	case st.drawOffer == side:
+		return reject(protocol.CodeAlreadyOffered, "you already offered a draw")
+	case st.seat(opponent(side)).bot != nil:
+		// the bot plays on
+		return reject(protocol.CodeDeclined, "BOT declines the draw")
+	}
+	st.drawOffer = side
+	m.broadcast(st, protocol.DrawOffered{By: st.seat(side).username})
+	return nil
+}
+
+func (m *Manager) acceptDraw(st *state, side string) *protocol.Error {
+	if st.over || st.drawOffer != opponent(side) {
+		return reject(protocol.CodeNoDrawOffer, "there is no draw offer to accept")
+	}
+	m.endGame(st, "Draw", "drawAgreed")
+	return nil
+}
+
+func (m *Manager) declineDraw(st *state, side string) *protocol.Error {
+	if st.over || st.drawOffer != opponent(side) {
+		return reject(protocol.CodeNoDrawOffer, "there is no draw offer to decline")
+	}
+	st.drawOffer = ""
+	m.broadcast(st, protocol.DrawDeclined{By: st.seat(side).username})
+	return nil
+}
+
+// requestRematch starts a new game with colours swapped once both players
+// have asked for it after the game ends. The bot always agrees.
+func (m *Manager) requestRematch(st *state, side string) *protocol.Error {
+	me, opp := st.seat(side), st.seat(opponent(side))
+	// called with m.mu held, for available
+	gone := func(pc *playerConn) bool {
+		return pc.bot == nil && (pc.conn == nil || !m.available(pc.username, st))
+	}
+	m.mu.Lock()
+	oppGone := gone(opp)
+	m.mu.Unlock()
+	switch {
+	case !st.over:
+		return reject(protocol.CodeGameInProgress, "finish this game first")
+	case st.next != nil || st.rematch == side:
+		return nil
+	case oppGone:
+		return reject(protocol.CodeOpponentLeft, opp.username+" has left")
+	case opp.bot == nil && st.rematch == "":
+		st.rematch = side
+		opp.conn.send(protocol.RematchOffered{By: me.username})
+		me.conn.send(protocol.Info{Message: "Rematch offered to " + opp.username})
+		return nil
+	}
+
+	// a fresh engine for the bot, the old one was closed with the game
+	var bot Engine
+	if opp.bot != nil {
+		var err error
+		if bot, err = NewEngine(st.opts.engine); err != nil {
+			log.Printf("engine %s: %v, using %s", st.opts.engine, err, Easy)
+			bot = Bot{Level: Easy}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closing || gone(me) || gone(opp) {
+		// shutting down, or one of them went off to another game meanwhile
+		if c, ok := bot.(io.Closer); ok {
+			_ = c.Close()
+		}
+		return nil
+	}
+	st.rematch = ""
+	// swap colours; both sockets already have a reader following st.next
+	p1 := playerConn{username: st.p2.username, conn: st.p2.conn, side: "R", bot: st.p2.bot, reading: true}
+	p2 := playerConn{username: st.p1.username, conn: st.p1.conn, side: "Y", bot: st.p1.bot, reading: true}
+	if p1.bot != nil {
+		p1.bot = bot
+	} else if p2.bot != nil {
+		p2.bot = bot
+	}
+	opts := st.opts
+	opts.room = ""
+	st.next = m.startGame(p1, p2, opts)
+	return nil
+}
+
+// available reports whether username is free for a rematch of st, i.e. not
+// already off in another game; caller holds m.mu.
+func (m *Manager) available(username string, st *state) bool {
+	ref := m.userToGame[username]
+	return ref == nil || ref.gameID == st.gameID
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResign(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, _ := pairUp(t, serve(t, m), "")
+	play(t, alice, bob, 3)
+	send(t, alice, "resign")
+	if msg := expect(t, bob, "gameOver"); msg["result"] != "bob wins" || msg["reason"] != "resign" {
+		t.Errorf("gameOver: %v", msg)
+	}
+	waitFor(t, "the result to be stored", func() bool { return storedPlayer(t, m, "bob").Wins == 1 })
+}
+
+func TestDrawOffers(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, _ := pairUp(t, serve(t, m), "")
+
+	send(t, bob, "acceptDraw", "seq", 1)
+	if msg := expect(t, bob, "error"); msg["code"] != "no_draw_offer" || msg["seq"] != float64(1) {
+		t.Errorf("accepting nothing: %v", msg)
+	}
+
+	send(t, alice, "offerDraw")
+	if msg := expect(t, bob, "drawOffered"); msg["by"] != "alice" {
+		t.Errorf("drawOffered: %v", msg)
+	}
+	send(t, alice, "offerDraw", "seq", 2)
+	if msg := expect(t, alice, "error"); msg["code"] != "already_offered" || msg["seq"] != float64(2) {
+		t.Errorf("offering twice: %v", msg)
+	}
+	send(t, bob, "declineDraw")
+	if msg := expect(t, alice, "drawDeclined"); msg["by"] != "bob" {
+		t.Errorf("drawDeclined: %v", msg)
+	}
+
+	// offering back and forth agrees a draw
+	send(t, alice, "offerDraw")
+	expect(t, bob, "drawOffered")
+	send(t, bob, "offerDraw")
+	if msg := expect(t, alice, "gameOver"); msg["result"] != "Draw" || msg["reason"] != "drawAgreed" {
+		t.Errorf("gameOver: %v", msg)
+	}
+	waitFor(t, "the draw to be stored", func() bool { return storedPlayer(t, m, "alice").Draws == 1 })
+}
+
+func TestRematch(t *testing.T) {
+	alice, bob, _ := pairUp(t, serve(t, testManager(time.Minute)), "")
+	send(t, alice, "rematch")
+	if msg := expect(t, alice, "error"); msg["code"] != "game_in_progress" {
+		t.Errorf("rematch mid-game: %v", msg)
+	}
+	send(t, alice, "resign")
+	expect(t, alice, "gameOver")
+	expect(t, bob, "gameOver")
+	send(t, bob, "resign")
+	if msg := expect(t, bob, "error"); msg["code"] != "game_over" {
+		t.Errorf("resigning a finished game: %v", msg)
+	}
+
+	send(t, alice, "rematch")
+	if msg := expect(t, bob, "rematchOffered"); msg["by"] != "alice" {
+		t.Errorf("rematchOffered: %v", msg)
+	}
+	send(t, bob, "rematch")
+	// colours swap
+	if msg := expect(t, bob, "start"); msg["color"] != "R" || msg["opponent"] != "alice" {
+		t.Errorf("bob's rematch start: %v", msg)
+	}
+	if msg := expect(t, alice, "start"); msg["color"] != "Y" {
+		t.Errorf("alice's rematch start: %v", msg)
+	}
+	// and the new game is live on the same sockets
+	play(t, bob, alice, 3)
+}
+
+func TestBotDeclinesDrawsAndTakesRematches(t *testing.T) {
+	m := testManager(time.Minute)
+	m.MatchBotAfter = 10 * time.Millisecond
+	alice := serve(t, m)(as("alice"))
+	expect(t, alice, "start")
+	send(t, alice, "offerDraw")
+	if msg := expect(t, alice, "error"); msg["code"] != "declined" {
+		t.Errorf("draw offer to the bot: %v", msg)
+	}
+	send(t, alice, "resign")
+	expect(t, alice, "gameOver")
+	send(t, alice, "rematch")
+	if msg := expect(t, alice, "start"); msg["color"] != "Y" || msg["opponent"] != "BOT" {
+		t.Errorf("rematch against the bot: %v", msg)
+	}
+}
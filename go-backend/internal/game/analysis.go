@@ -0,0 +1,203 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Outcomes of a position or a move for the side to move, as far as the
+// search could tell.
+const (
+	OutcomeWin     = "win"
+	OutcomeDraw    = "draw"
+	OutcomeLoss    = "loss"
+	OutcomeUnknown = "unknown" // not solved in the time given; see Score
+	OutcomeFull    = "full"    // the column can't be played
+)
+
+// ColumnEval is what playing one column leads to.
+type ColumnEval struct {
+	Col     int    `json:"col"`
+	Outcome string `json:"outcome"`
+	Plies   int    `json:"plies,omitempty"` // discs until the forced result, this one included
+	Score   int    `json:"score,omitempty"` // the engine's guess when unknown; higher is better for the mover
+}
+
+// Analysis rates every column of a position for the side to move.
+type Analysis struct {
+	ToMove   string       `json:"toMove"`
+	Outcome  string       `json:"outcome"` // of the position, with best play
+	Plies    int          `json:"plies,omitempty"`
+	BestMove int          `json:"bestMove"` // -1 with no legal move
+	PV       []int        `json:"pv"`       // best play from here, starting with BestMove
+	Columns  []ColumnEval `json:"columns"`  // one per column, left to right
+	Solved   bool         `json:"solved"`   // every playable column has a known outcome
+	Nodes    int          `json:"nodes"`
+}
+
+// PlayMoves plays 0-based columns from the empty board, R first, and
+// fails on a move that can't be made.
+func PlayMoves(v Variant, moves []int) (*GameLogic, error) {
+	g, err := NewVariantGame(v)
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range moves {
+		if err := g.Playable(); err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		if col < 0 || col >= g.Cols {
+			return nil, fmt.Errorf("move %d: column %d is off the board (0-%d)", i+1, col, g.Cols-1)
+		}
+		if _, ok := g.DropDisc(col, sideToMove(i)); !ok {
+			return nil, fmt.Errorf("move %d: column %d is full", i+1, col)
+		}
+	}
+	return g, nil
+}
+
+// Playable says why g isn't a position someone could be to move in: the
+// disc counts don't alternate from R, somebody already won, or the board
+// is full.
+func (g *GameLogic) Playable() error {
+	r, y := g.discs[0].count(), g.discs[1].count()
+	switch {
+	case r != y && r != y+1:
+		return fmt.Errorf("%d red and %d yellow discs can't happen with R moving first", r, y)
+	case g.wins(0) || g.wins(1):
+		return errors.New("the game is already won")
+	case g.IsFull():
+		return errors.New("the board is full")
+	}
+	return nil
+}
+
+// Analyze searches every playable column for the side to move, deepening
+// them together so their scores stay comparable, until they're all solved
+// or budget runs out (budget <= 0 means no limit). A column that isn't
+// solved comes back OutcomeUnknown with the engine's heuristic score from
+// the deepest search every column finished.
+func Analyze(ctx context.Context, g *GameLogic, budget time.Duration) Analysis {
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	player := g.ToMove()
+	a := Analysis{ToMove: player, Outcome: OutcomeUnknown, BestMove: -1, Columns: make([]ColumnEval, g.Cols)}
+
+	type column struct {
+		pos   *GameLogic
+		empty int  // cells left after the move
+		done  bool // score is final
+		score int
+		pv    []int
+	}
+	cols := make([]*column, g.Cols)
+	open := 0
+	for c := 0; c < g.Cols; c++ {
+		if !g.ValidColumn(c) {
+			continue
+		}
+		col := &column{pos: g.Clone(), pv: []int{c}}
+		col.pos.DropDisc(c, player)
+		col.empty = col.pos.Rows*col.pos.Cols - col.pos.moves
+		switch {
+		case col.pos.CheckWinner(player):
+			col.score, col.done = winScore-1, true
+		case col.pos.IsFull():
+			col.score, col.done = 0, true
+		default:
+			open++
+		}
+		cols[c] = col
+	}
+
+	// Even depths only: the heuristic see-saws with whose turn it is at the
+	// leaves, so mixing depths would favour whichever column got the lucky
+	// one. A round only counts if every column finished it.
+	for depth := 2; open > 0; depth += 2 {
+		scores := make([]int, g.Cols)
+		pvs := make([][]int, g.Cols)
+		solved := make([]bool, g.Cols)
+		complete := true
+		for c, col := range cols {
+			if col == nil || col.done {
+				continue
+			}
+			res := SearchContext(ctx, col.pos, opponent(player), depth, 0)
+			a.Nodes += res.Nodes
+			if !res.Solved() && res.Depth < min(depth, col.empty) {
+				complete = false
+				break
+			}
+			scores[c], pvs[c] = rootScore(res), append([]int{c}, res.PV...)
+			solved[c] = res.Solved() || res.Depth == col.empty
+		}
+		if !complete {
+			break
+		}
+		for c, col := range cols {
+			if col == nil || col.done {
+				continue
+			}
+			col.score, col.pv, col.done = scores[c], pvs[c], solved[c]
+			if col.done {
+				open--
+			}
+		}
+	}
+
+	a.Solved = true
+	for c, col := range cols {
+		ev := ColumnEval{Col: c, Outcome: OutcomeFull}
+		if col != nil {
+			ev.Outcome, ev.Plies = outcomeOf(col.score, col.done)
+			if ev.Outcome == OutcomeUnknown {
+				ev.Score = col.score
+				a.Solved = false
+			}
+		}
+		a.Columns[c] = ev
+	}
+	// ties go to the middle, like the bot
+	for _, c := range CenterOrder(g.Cols) {
+		if cols[c] != nil && (a.BestMove < 0 || cols[c].score > cols[a.BestMove].score) {
+			a.BestMove = c
+		}
+	}
+	if a.BestMove >= 0 {
+		best := a.Columns[a.BestMove]
+		a.Outcome, a.Plies, a.PV = best.Outcome, best.Plies, cols[a.BestMove].pv
+	}
+	return a
+}
+
+// rootScore turns a search from the opponent's side after our move into
+// our score for that move, one ply further from any forced result.
+func rootScore(res SearchResult) int {
+	switch {
+	case res.Score < -mateBound:
+		return -res.Score - 1
+	case res.Score > mateBound:
+		return -res.Score + 1
+	}
+	return -res.Score
+}
+
+// outcomeOf reads a score for the side to move; exact says a score that
+// isn't a forced result comes from a search to the end of the game, so
+// it's a draw rather than a guess.
+func outcomeOf(score int, exact bool) (outcome string, plies int) {
+	switch {
+	case score > mateBound:
+		return OutcomeWin, winScore - score
+	case score < -mateBound:
+		return OutcomeLoss, winScore + score
+	case exact:
+		return OutcomeDraw, 0
+	}
+	return OutcomeUnknown, 0
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlayMovesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     Variant
+		moves []int
+		want  string
+	}{
+		{"off the board", Standard, []int{3, 7}, "move 2: column 7 is off the board"},
+		{"negative", Standard, []int{-1}, "move 1: column -1 is off the board"},
+		{"full column", Variant{Rows: 2, Cols: 3, Connect: 2}, []int{0, 0, 0}, "move 3: column 0 is full"},
+		{"after the win", Standard, []int{0, 1, 0, 1, 0, 1, 0, 1}, "move 8: the game is already won"},
+		{"after the board filled", Variant{Rows: 1, Cols: 2, Connect: 2}, []int{0, 1, 0}, "move 3: the board is full"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PlayMoves(tt.v, tt.moves); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayable(t *testing.T) {
+	for pos, want := range map[string]string{
+		"...\n...\n...":   "",
+		"...\n...\nRY.":   "",
+		"...\n...\nRR.":   "2 red and 0 yellow discs",
+		"...\n...\nY..":   "0 red and 1 yellow discs",
+		"...\nRY.\nRY.\n": "",
+		"R..\nRY.\nRY.":   "already won",
+		"RYR\nRYR\nYRY":   "full",
+	} {
+		g, err := ParsePosition(strings.ReplaceAll(strings.TrimSpace(pos), "\n", "/"), 3)
+		if err != nil {
+			t.Fatalf("%q: %v", pos, err)
+		}
+		err = g.Playable()
+		if want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("%q: Playable() = %v, want %q", pos, err, want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	// R has three up column 0 and Y three up column 1: R wins at once
+	// there, and anything else but the block loses in two
+	g := playMoves(t, Standard, []int{0, 1, 0, 1, 0, 1})
+	a := Analyze(context.Background(), g, time.Second)
+	if a.ToMove != "R" || a.Outcome != OutcomeWin || a.Plies != 1 || a.BestMove != 0 || len(a.PV) != 1 || a.PV[0] != 0 {
+		t.Fatalf("analysis: %+v", a)
+	}
+	for c := 2; c < 7; c++ {
+		if ev := a.Columns[c]; ev.Outcome != OutcomeLoss || ev.Plies != 2 {
+			t.Errorf("column %d: %+v, want a loss in 2", c, ev)
+		}
+	}
+}
+
+func TestAnalyzeSmallBoard(t *testing.T) {
+	// the first player wins 4x4 connect 3; with column 0 full it's a draw
+	// or a loss everywhere else
+	a := Analyze(context.Background(), playMoves(t, Variant{Rows: 4, Cols: 4, Connect: 3}, nil), 10*time.Second)
+	if !a.Solved || a.Outcome != OutcomeWin || a.Columns[a.BestMove].Outcome != OutcomeWin {
+		t.Fatalf("empty 4x4c3: %+v", a)
+	}
+
+	g := playMoves(t, Variant{Rows: 2, Cols: 3, Connect: 3}, []int{0, 0})
+	a = Analyze(context.Background(), g, 10*time.Second)
+	if !a.Solved || a.Columns[0].Outcome != OutcomeFull || a.Outcome != OutcomeDraw || a.BestMove == 0 {
+		t.Fatalf("2x3c3 with a full column: %+v", a)
+	}
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+// Finished games are annotated in the background: every position is run
+// through Analyze and the move played is compared with the best one. One
+// game at a time, so a busy server spends at most a core on it; games
+// finishing faster than that are skipped rather than queued forever.
+
+const (
+	annotateQueue = 64
+
+	// heuristic score drops, for moves measured against the engine's guess
+	inaccuracyDrop = 2
+	mistakeDrop    = 10
+)
+
+type annotateJob struct {
+	gameID  string
+	variant Variant
+	moves   []int
+}
+
+// queueAnnotation hands a stored game to annotateLoop, if annotating is on
+// and the queue has room.
+func (m *Manager) queueAnnotation(gameID string, v Variant, moves []models.Move) {
+	if m.AnnotateMove <= 0 || len(moves) == 0 {
+		return
+	}
+	job := annotateJob{gameID: gameID, variant: v, moves: make([]int, len(moves))}
+	for i, mv := range moves {
+		job.moves[i] = mv.Col
+	}
+	select {
+	case m.annotations <- job:
+	default:
+		log.Printf("game %s: annotation queue full, skipping", gameID)
+	}
+}
+
+func (m *Manager) annotateLoop() {
+	for job := range m.annotations {
+		notes, err := Annotate(context.Background(), job.variant, job.moves, m.AnnotateMove)
+		if err == nil {
+			err = m.Store.AnnotateGame(context.Background(), job.gameID, notes)
+		}
+		if err != nil {
+			log.Printf("game %s: annotate: %v", job.gameID, err)
+		}
+	}
+}
+
+// Annotate rates every move of a game, giving Analyze perMove for each
+// position.
+func Annotate(ctx context.Context, v Variant, moves []int, perMove time.Duration) ([]models.MoveNote, error) {
+	g, err := NewVariantGame(v)
+	if err != nil {
+		return nil, err
+	}
+	notes := make([]models.MoveNote, 0, len(moves))
+	for i, col := range moves {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		a := Analyze(ctx, g, perMove)
+		if a.BestMove < 0 || col < 0 || col >= g.Cols {
+			break // a stored game never gets here
+		}
+		notes = append(notes, models.MoveNote{
+			Class: classify(a.Columns[a.BestMove], a.Columns[col]),
+			Best:  a.BestMove,
+		})
+		g.DropDisc(col, sideToMove(i))
+	}
+	return notes, nil
+}
+
+// classify compares the move played with the engine's choice. A proven
+// loss is a blunder unless the engine's move loses too, and giving up a
+// proven win is a mistake, whatever is known about the other move. When
+// both outcomes are the same proven one, a slower win or a quicker loss is
+// an inaccuracy. Against a guess it's down to how far the heuristic score
+// dropped (a proven draw scores 0), which never makes a blunder. Only a
+// guess played where the engine's move is proven can't be compared, so
+// that move is NoteUnknown.
+func classify(best, played ColumnEval) string {
+	switch {
+	case played.Col == best.Col:
+		return models.NoteBest
+	case played.Outcome == OutcomeLoss && best.Outcome != OutcomeLoss:
+		return models.NoteBlunder
+	case best.Outcome == OutcomeWin && played.Outcome != OutcomeWin:
+		return models.NoteMistake
+	case played.Outcome == OutcomeUnknown && best.Outcome != OutcomeUnknown:
+		return models.NoteUnknown
+	case played.Outcome == OutcomeWin && best.Outcome != OutcomeWin:
+		return models.NoteBest // nothing beats a win
+	case best.Outcome == OutcomeUnknown:
+		switch drop := best.Score - played.Score; {
+		case drop <= inaccuracyDrop:
+			return models.NoteBest
+		case drop <= mistakeDrop:
+			return models.NoteInaccuracy
+		}
+		return models.NoteMistake
+	case played.Outcome != best.Outcome, played.Plies == best.Plies:
+		// a draw where the engine's move loses, or as good as its move
+		return models.NoteBest
+	}
+	return models.NoteInaccuracy
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+func TestClassify(t *testing.T) {
+	win := func(col, plies int) ColumnEval { return ColumnEval{Col: col, Outcome: OutcomeWin, Plies: plies} }
+	draw := func(col int) ColumnEval { return ColumnEval{Col: col, Outcome: OutcomeDraw} }
+	loss := func(col, plies int) ColumnEval { return ColumnEval{Col: col, Outcome: OutcomeLoss, Plies: plies} }
+	guess := func(col, score int) ColumnEval { return ColumnEval{Col: col, Outcome: OutcomeUnknown, Score: score} }
+	tests := []struct {
+		name         string
+		best, played ColumnEval
+		want         string
+	}{
+		{"the engine's move", guess(3, 5), guess(3, 5), models.NoteBest},
+		{"as quick a win", win(3, 5), win(2, 5), models.NoteBest},
+		{"a slower win", win(3, 5), win(2, 9), models.NoteInaccuracy},
+		{"a quicker loss", loss(3, 8), loss(2, 2), models.NoteInaccuracy},
+		{"a draw thrown away", draw(3), loss(2, 4), models.NoteBlunder},
+		{"a win thrown away", win(3, 5), draw(2), models.NoteMistake},
+		{"a win for a loss", win(3, 5), loss(2, 4), models.NoteBlunder},
+		{"a loss when best is a guess", guess(3, 0), loss(2, 2), models.NoteBlunder},
+		{"a guess for a win", win(3, 7), guess(2, 40), models.NoteMistake},
+		{"a guess for a draw", draw(3), guess(2, 5), models.NoteUnknown},
+		{"a guess for a slow loss", loss(3, 12), guess(2, -5), models.NoteUnknown},
+		{"a win when best is a guess", guess(3, 30), win(2, 9), models.NoteBest},
+		{"a draw for a good guess", guess(3, 20), draw(2), models.NoteMistake},
+		{"a draw for an even guess", guess(3, 1), draw(2), models.NoteBest},
+		{"a small drop", guess(3, 10), guess(2, 8), models.NoteBest},
+		{"a drop", guess(3, 10), guess(2, 1), models.NoteInaccuracy},
+		{"a big drop", guess(3, 10), guess(2, -20), models.NoteMistake},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.best, tt.played); got != tt.want {
+			t.Errorf("%s: classify(%+v, %+v) = %s, want %s", tt.name, tt.best, tt.played, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	// R stacks column 3; Y blocks twice, then looks away with 6 and R
+	// connects
+	notes, err := Annotate(context.Background(), Standard, []int{3, 0, 3, 0, 3, 6, 3}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 7 {
+		t.Fatalf("%d notes for 7 moves: %+v", len(notes), notes)
+	}
+	if n := notes[5]; n.Class != models.NoteBlunder || n.Best != 3 {
+		t.Errorf("Y's 6: %+v, want a blunder with 3 best", n)
+	}
+	if n := notes[6]; n.Class != models.NoteBest || n.Best != 3 {
+		t.Errorf("R's winning 3: %+v", n)
+	}
+}
+
+func TestFinishedGamesAreAnnotated(t *testing.T) {
+	m := testManager(time.Minute)
+	m.AnnotateMove = 20 * time.Millisecond
+	alice, bob, gameID := pairUp(t, serve(t, m), "")
+	play(t, alice, bob, 0, 1, 0, 1, 0, 1, 0)
+	expect(t, alice, "gameOver")
+
+	var g models.GameDoc
+	waitFor(t, "the notes", func() bool {
+		g, _ = m.Store.GetGame(context.Background(), gameID)
+		return g.Notes != nil
+	})
+	if len(g.Notes) != 7 || g.Notes[6].Class != models.NoteBest {
+		t.Errorf("notes: %+v", g.Notes)
+	}
+	// moves the engine couldn't compare aren't tallied
+	if a, b := storedPlayer(t, m, "alice"), storedPlayer(t, m, "bob"); a.Annotated.Total() == 0 || a.Annotated.Total() > 4 || b.Annotated.Total() > 3 {
+		t.Errorf("tallies: alice %+v, bob %+v", a.Annotated, b.Annotated)
+	}
+}
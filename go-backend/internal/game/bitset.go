@@ -0,0 +1,40 @@
+package game
+
+import "math/bits"
+
+// bitset is a 128-bit mask, enough for any board where (Rows+1)*Cols <= 128
+// (e.g. 8x9 takes 81 bits). It's a value type so Clone stays allocation-free.
+type bitset struct {
+	lo, hi uint64
+}
+
+const maxBits = 128
+
+func bitAt(i int) bitset {
+	if i < 64 {
+		return bitset{lo: 1 << uint(i)}
+	}
+	return bitset{hi: 1 << uint(i-64)}
+}
+
+func (b bitset) and(o bitset) bitset { return bitset{b.lo & o.lo, b.hi & o.hi} }
+func (b bitset) or(o bitset) bitset  { return bitset{b.lo | o.lo, b.hi | o.hi} }
+func (b bitset) not() bitset         { return bitset{^b.lo, ^b.hi} }
+func (b bitset) isZero() bool        { return b.lo == 0 && b.hi == 0 }
+func (b bitset) has(i int) bool      { return !b.and(bitAt(i)).isZero() }
+
+func (b bitset) count() int {
+	return bits.OnesCount64(b.lo) + bits.OnesCount64(b.hi)
+}
+
+func (b bitset) shr(n uint) bitset {
+	switch {
+	case n == 0:
+		return b
+	case n >= 128:
+		return bitset{}
+	case n >= 64:
+		return bitset{lo: b.hi >> (n - 64)}
+	}
+	return bitset{lo: b.lo>>n | b.hi<<(64-n), hi: b.hi >> n}
+}
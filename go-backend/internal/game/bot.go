@@ -1,24 +1,87 @@
 package game
 
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Difficulty picks how hard the bot thinks. Easy is the original one-ply
+// bot; the others run Search with growing depth and time budgets. Expert
+// searches until the game is solved, but gives up after 5s with its best
+// guess. Perfect never gives up: it solves every position before it moves,
+// however long that takes and whatever its clock says.
+type Difficulty string
+
+const (
+	Easy    Difficulty = "easy"
+	Medium  Difficulty = "medium"
+	Hard    Difficulty = "hard"
+	Expert  Difficulty = "expert"
+	Perfect Difficulty = "perfect"
+)
+
+type searchLimits struct {
+	depth  int           // 0 = until solved
+	budget time.Duration // 0 = no limit
+}
+
+var difficultyLimits = map[Difficulty]searchLimits{
+	Medium:  {depth: 4, budget: 250 * time.Millisecond},
+	Hard:    {depth: 10, budget: time.Second},
+	Expert:  {depth: 0, budget: 5 * time.Second},
+	Perfect: {depth: 0, budget: 0},
+}
+
+// ParseDifficulty accepts the ?difficulty= value; empty means Easy.
+func ParseDifficulty(s string) (Difficulty, error) {
+	switch d := Difficulty(s); d {
+	case "":
+		return Easy, nil
+	case Easy, Medium, Hard, Expert, Perfect:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown difficulty %q", s)
+}
+
+// Bot is the built-in engine: one-ply greedy at Easy, Search above that.
 type Bot struct {
-	Symbol string // "Y"
+	Level Difficulty
 }
 
-func (b Bot) opp() string {
-	if b.Symbol == "R" { return "Y" }
+func opponent(player string) string {
+	if player == "R" {
+		return "Y"
+	}
 	return "R"
 }
 
-func (b Bot) ChooseMove(g *GameLogic) int {
+func (b Bot) ChooseMove(ctx context.Context, g *GameLogic, player string, remaining time.Duration) (int, error) {
+	if lim, ok := difficultyLimits[b.Level]; ok {
+		budget := lim.budget
+		// never spend more than a quarter of what's left on the clock;
+		// Perfect has no budget to cut
+		if remaining > 0 && budget > 0 && remaining/4 < budget {
+			budget = remaining / 4
+		}
+		if res := SearchContext(ctx, g, player, lim.depth, budget); res.Col >= 0 {
+			return res.Col, nil
+		}
+	}
+	return greedyMove(g, player), nil
+}
+
+// greedyMove looks one ply ahead: win now, block, then centre first.
+func greedyMove(g *GameLogic, player string) int {
 	// win now
 	for c := 0; c < g.Cols; c++ {
 		clone := g.Clone()
-		if _, ok := clone.DropDisc(c, b.Symbol); ok && clone.CheckWinner(b.Symbol) {
+		if _, ok := clone.DropDisc(c, player); ok && clone.CheckWinner(player) {
 			return c
 		}
 	}
 	// block opp
-	opp := b.opp()
+	opp := opponent(player)
 	for c := 0; c < g.Cols; c++ {
 		clone := g.Clone()
 		if _, ok := clone.DropDisc(c, opp); ok && clone.CheckWinner(opp) {
@@ -26,12 +89,15 @@ func (b Bot) ChooseMove(g *GameLogic) int {
 		}
 	}
 	// heuristic: center then outwards
-	order := []int{3, 2, 4, 1, 5, 0, 6}
-	for _, c := range order {
-		if g.ValidColumn(c) { return c }
+	for _, c := range CenterOrder(g.Cols) {
+		if g.ValidColumn(c) {
+			return c
+		}
 	}
 	for c := 0; c < g.Cols; c++ {
-		if g.ValidColumn(c) { return c }
+		if g.ValidColumn(c) {
+			return c
+		}
 	}
 	return 0
 }
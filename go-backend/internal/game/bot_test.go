@@ -0,0 +1,57 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseDifficulty(t *testing.T) {
+	for in, want := range map[string]Difficulty{"": Easy, "easy": Easy, "medium": Medium, "hard": Hard, "expert": Expert, "perfect": Perfect} {
+		if got, err := ParseDifficulty(in); err != nil || got != want {
+			t.Errorf("ParseDifficulty(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDifficulty("impossible"); err == nil {
+		t.Error("ParseDifficulty accepted an unknown level")
+	}
+}
+
+func TestBotWinsAndBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		moves  []int // R first; the bot plays Y
+		want   int
+		levels []Difficulty
+	}{
+		// Y has three across the bottom and R three up column 6
+		{"takes the win", []int{6, 0, 6, 1, 6, 2, 5}, 3, []Difficulty{Easy, Medium, Hard, Expert, Perfect}},
+		// R has three across the bottom; expert and perfect would go on to
+		// solve the rest of the game
+		{"blocks", []int{0, 6, 1, 6, 2}, 3, []Difficulty{Easy, Medium, Hard}},
+	}
+	for _, tt := range tests {
+		for _, level := range tt.levels {
+			g := playMoves(t, Standard, tt.moves)
+			got, err := Bot{Level: level}.ChooseMove(context.Background(), g, "Y", 0)
+			if err != nil || got != tt.want {
+				t.Errorf("%s, %s: played %d, %v; want %d", tt.name, level, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestPerfectSolves(t *testing.T) {
+	// 4x4 connect 3 is a win for whoever starts, however little clock
+	// they have; after perfect's first move the other side must be lost
+	v := Variant{Rows: 4, Cols: 4, Connect: 3}
+	g := playMoves(t, v, nil)
+	col, err := Bot{Level: Perfect}.ChooseMove(context.Background(), g, "R", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.DropDisc(col, "R")
+	if res := Search(g, "Y", 0, 0); res.Score >= -mateBound {
+		t.Errorf("R played %d, but Y isn't lost: %+v", col, res)
+	}
+}
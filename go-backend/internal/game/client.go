@@ -0,0 +1,125 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+const (
+	writeWait      = 10 * time.Second  // max time for one write to the peer
+	pongWait       = 60 * time.Second  // the peer must answer a ping within this
+	pingPeriod     = pongWait * 9 / 10 // how often we ping, a little under pongWait
+	maxMessageSize = 4096              // client messages are tiny; anything bigger is junk
+	sendQueueSize  = 64                // messages buffered per client before we give up on it
+)
+
+// client is one WebSocket connection. gorilla/websocket allows only one
+// writer at a time, and a slow peer mustn't hold m.mu while it catches up,
+// so messages are queued with send and written by the client's own
+// goroutine. That goroutine also pings the peer; a peer that stops answering
+// hits the read deadline, and one that can't keep up with its queue is
+// dropped. Either way its reader sees the socket close and the usual
+// disconnect handling takes over.
+type client struct {
+	ws   *websocket.Conn
+	out  chan []byte
+	done chan struct{} // closed once the writer has stopped
+
+	mu      sync.Mutex
+	closing bool // no more sends; out is closed unless we evicted it
+}
+
+func newClient(ws *websocket.Conn) *client {
+	c := &client{ws: ws, out: make(chan []byte, sendQueueSize), done: make(chan struct{})}
+	ws.SetReadLimit(maxMessageSize)
+	_ = ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	go c.writeLoop()
+	return c
+}
+
+// read returns the next message from the peer. Once it fails the socket is
+// done for, so the writer is stopped too.
+func (c *client) read() ([]byte, error) {
+	_, msg, err := c.ws.ReadMessage()
+	if err != nil {
+		c.close()
+	}
+	return msg, err
+}
+
+// send queues msg for the peer. It never blocks: if the queue is full the
+// client is evicted. Safe on a nil client (an empty bot seat).
+func (c *client) send(msg protocol.Message) {
+	if c == nil {
+		return
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("encode %s: %v", msg.MsgType(), err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return
+	}
+	select {
+	case c.out <- b:
+	default:
+		log.Printf("client %s: send queue full, dropping connection", c.ws.RemoteAddr())
+		c.closing = true
+		_ = c.ws.Close()
+	}
+}
+
+// close hangs up once everything already queued has been written.
+func (c *client) close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return
+	}
+	c.closing = true
+	close(c.out)
+}
+
+func (c *client) writeLoop() {
+	ping := time.NewTicker(pingPeriod)
+	defer func() {
+		ping.Stop()
+		c.mu.Lock()
+		c.closing = true
+		c.mu.Unlock()
+		_ = c.ws.Close()
+		close(c.done)
+	}()
+	for {
+		select {
+		case b, ok := <-c.out:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.ws.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		case <-ping.C:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+func TestClientFlushesBeforeClosing(t *testing.T) {
+	c, peer := wsPair(t)
+	c.send(protocol.Info{Message: "one"})
+	c.send(protocol.Info{Message: "two"})
+	c.close()
+	c.send(protocol.Info{Message: "too late"})
+	c.close() // twice is fine
+
+	var got []string
+	for {
+		var msg map[string]any
+		_ = peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := peer.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Fatalf("after %v: %v, want a normal close", got, err)
+			}
+			break
+		}
+		got = append(got, msg["message"].(string))
+	}
+	if strings.Join(got, ",") != "one,two" {
+		t.Errorf("peer got %v, want [one two]", got)
+	}
+}
+
+func TestClientDropsAPeerThatFallsBehind(t *testing.T) {
+	c, peer := wsPair(t)
+	// the peer never reads, so the socket buffers fill and then the queue
+	big := protocol.Info{Message: strings.Repeat("x", 64<<10)}
+	for i := 0; i < 10*sendQueueSize; i++ {
+		c.send(big)
+	}
+	c.mu.Lock()
+	closing := c.closing
+	c.mu.Unlock()
+	if !closing {
+		t.Fatal("still queueing for a peer that isn't reading")
+	}
+	if _, err := c.read(); err == nil {
+		t.Fatal("read from an evicted client")
+	}
+	_ = peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := peer.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
+
+// A send to an empty seat goes nowhere.
+func TestNilClient(t *testing.T) {
+	var c *client
+	c.send(protocol.Info{Message: "hello?"})
+	c.close()
+}
+
+// When the peer hangs up, the reader's error stops the writer as well.
+func TestClientStopsWritingWhenThePeerGoes(t *testing.T) {
+	c, peer := wsPair(t)
+	_ = peer.Close()
+	if _, err := c.read(); err == nil {
+		t.Fatal("read from a closed socket")
+	}
+	c.mu.Lock()
+	closing := c.closing
+	c.mu.Unlock()
+	if !closing {
+		t.Error("writer still running after the read failed")
+	}
+}
@@ -0,0 +1,192 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// TimeControl is how much thinking time each side gets. The zero value means
+// untimed. With PerMove set every move gets that long and nothing carries
+// over; otherwise it's a chess clock with Base per side plus Increment
+// added after each move.
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+	PerMove   time.Duration
+}
+
+// Untimed is the zero TimeControl.
+var Untimed TimeControl
+
+const maxClock = 3 * time.Hour
+
+func (tc TimeControl) Enabled() bool { return tc != Untimed }
+
+// String gives the form ParseTimeControl reads: "60+2", "15/move" or "".
+func (tc TimeControl) String() string {
+	switch {
+	case tc.PerMove > 0:
+		return fmtSeconds(tc.PerMove) + "/move"
+	case tc.Enabled():
+		return fmtSeconds(tc.Base) + "+" + fmtSeconds(tc.Increment)
+	}
+	return ""
+}
+
+func fmtSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// ParseTimeControl reads "<base>+<increment>" or "<seconds>/move", all in
+// seconds. A space works in place of the plus since that's what an
+// unescaped "+" turns into in a query string. Empty means untimed.
+func ParseTimeControl(s string) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Untimed, nil
+	}
+	secs := func(f string) (time.Duration, error) {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time control %q", s)
+		}
+		return time.Duration(n * float64(time.Second)), nil
+	}
+	var tc TimeControl
+	var err error
+	if per, ok := strings.CutSuffix(s, "/move"); ok {
+		if tc.PerMove, err = secs(per); err != nil {
+			return tc, err
+		}
+	} else {
+		base, inc, _ := strings.Cut(strings.Replace(s, " ", "+", 1), "+")
+		if tc.Base, err = secs(base); err != nil {
+			return tc, err
+		}
+		if inc != "" {
+			if tc.Increment, err = secs(inc); err != nil {
+				return tc, err
+			}
+		}
+	}
+	if tc.PerMove == 0 && tc.Base == 0 {
+		return tc, fmt.Errorf("time control %q gives no time", s)
+	}
+	if tc.Base > maxClock || tc.PerMove > maxClock || tc.Increment > maxClock {
+		return tc, fmt.Errorf("time control %q is too long", s)
+	}
+	return tc, nil
+}
+
+// clock is the server's copy of both players' time. Like the rest of the
+// state it's only touched on the game's goroutine.
+type clock struct {
+	tc    TimeControl
+	left  map[string]time.Duration // "R"/"Y" -> time left, as of since
+	since time.Time                // when the side to move started thinking
+	flag  *time.Timer
+	// restored and nobody back yet: neither clock runs until the first
+	// rejoin, see unpause
+	paused bool
+}
+
+func (c *clock) initial() time.Duration {
+	if c.tc.PerMove > 0 {
+		return c.tc.PerMove
+	}
+	return c.tc.Base
+}
+
+// remaining is what side has left right now.
+func (c *clock) remaining(side, turn string) time.Duration {
+	left := c.left[side]
+	if side == turn && !c.paused {
+		left -= time.Since(c.since)
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// payload is the clock part of start/update messages; nil when untimed.
+func (c *clock) payload(turn string) *protocol.Clock {
+	if !c.tc.Enabled() {
+		return nil
+	}
+	return &protocol.Clock{
+		R:           c.remaining("R", turn).Milliseconds(),
+		Y:           c.remaining("Y", turn).Milliseconds(),
+		TimeControl: c.tc.String(),
+	}
+}
+
+func (c *clock) stop() {
+	if c.flag != nil {
+		c.flag.Stop()
+		c.flag = nil
+	}
+}
+
+// startClock sets both clocks and starts R's; caller holds m.mu, the game's
+// goroutine isn't running yet.
+func (m *Manager) startClock(st *state) {
+	if !st.clock.tc.Enabled() {
+		return
+	}
+	st.clock.left = map[string]time.Duration{"R": st.clock.initial(), "Y": st.clock.initial()}
+	m.runClock(st, "R")
+}
+
+// punchClock charges side for the move it just made and starts the other
+// clock. It reports false if side's flag had already fallen, in which case
+// the move doesn't count. Runs on st's goroutine.
+func (m *Manager) punchClock(st *state, side string) bool {
+	c := &st.clock
+	if !c.tc.Enabled() {
+		return true
+	}
+	c.stop()
+	left := c.left[side] - time.Since(c.since)
+	if left <= 0 {
+		c.left[side] = 0
+		m.flagFall(st, side)
+		return false
+	}
+	if c.tc.PerMove > 0 {
+		left = c.tc.PerMove
+	} else {
+		left += c.tc.Increment
+	}
+	c.left[side] = left
+	m.runClock(st, opponent(side))
+	return true
+}
+
+func (m *Manager) runClock(st *state, side string) {
+	st.clock.since = time.Now()
+	st.clock.flag = time.AfterFunc(st.clock.left[side], func() {
+		st.post(func() {
+			// the move may have landed while this was queued
+			if st.over || st.turn != side || st.clock.remaining(side, side) > 0 {
+				return
+			}
+			st.clock.left[side] = 0
+			m.flagFall(st, side)
+		})
+	})
+}
+
+// flagFall ends the game as a loss for side; runs on st's goroutine.
+func (m *Manager) flagFall(st *state, side string) {
+	loser, winner := st.p1.username, st.p2.username
+	if side == "Y" {
+		loser, winner = winner, loser
+	}
+	m.broadcast(st, protocol.Info{Message: loser + " ran out of time"})
+	m.endGame(st, winner, "timeout")
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		in   string
+		want TimeControl
+		err  string
+	}{
+		{"", Untimed, ""},
+		{"60+2", TimeControl{Base: time.Minute, Increment: 2 * time.Second}, ""},
+		{"60 2", TimeControl{Base: time.Minute, Increment: 2 * time.Second}, ""}, // an unescaped +
+		{"300", TimeControl{Base: 5 * time.Minute}, ""},
+		{"15/move", TimeControl{PerMove: 15 * time.Second}, ""},
+		{"0.5+0.1", TimeControl{Base: 500 * time.Millisecond, Increment: 100 * time.Millisecond}, ""},
+		{"0+5", Untimed, "gives no time"},
+		{"-1+1", Untimed, "invalid"},
+		{"ten", Untimed, "invalid"},
+		{"36000", Untimed, "too long"},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeControl(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseTimeControl(%q): error %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTimeControl(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+		if back, err := ParseTimeControl(got.String()); err != nil || back != got {
+			t.Errorf("%q doesn't read back: %+v, %v", got.String(), back, err)
+		}
+	}
+}
+
+func clockOf(t *testing.T, msg map[string]any) (r, y float64) {
+	t.Helper()
+	c, ok := msg["clock"].(map[string]any)
+	if !ok {
+		t.Fatalf("no clock in %v", msg)
+	}
+	return c["R"].(float64), c["Y"].(float64)
+}
+
+func TestClockIncrement(t *testing.T) {
+	dial := serve(t, testManager(time.Minute))
+	alice := dial(as("alice") + "&time=5+2")
+	expect(t, alice, "queued")
+	bob := dial(as("bob") + "&time=5+2")
+	// red's is already running
+	if r, y := clockOf(t, expect(t, alice, "start")); r < 4900 || r > 5000 || y != 5000 {
+		t.Errorf("start clock %v/%v", r, y)
+	}
+	expect(t, bob, "start")
+	if err := alice.WriteJSON(map[string]any{"type": "move", "col": 3}); err != nil {
+		t.Fatal(err)
+	}
+	// red spent a moment and got two seconds back
+	if r, y := clockOf(t, expect(t, bob, "update")); r <= 6500 || r > 7000 || y > 5000 || y < 4500 {
+		t.Errorf("after red's move: %v/%v", r, y)
+	}
+}
+
+func TestFlagFall(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, _ := pairUp(t, serve(t, m), "&time=0.3/move")
+	play(t, alice, bob, 3, 3, 2)
+	// yellow sits on it
+	if msg := expect(t, alice, "info"); !strings.Contains(msg["message"].(string), "bob ran out of time") {
+		t.Errorf("info: %v", msg)
+	}
+	if msg := expect(t, bob, "gameOver"); msg["result"] != "alice wins" {
+		t.Errorf("gameOver: %v", msg)
+	}
+	waitFor(t, "the result to be stored", func() bool { return storedPlayer(t, m, "alice").Wins == 1 })
+
+	// a move after the flag fell doesn't count
+	if err := bob.WriteJSON(map[string]any{"type": "move", "col": 2}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if p := storedPlayer(t, m, "bob"); p.Losses != 1 || p.Wins != 0 {
+		t.Errorf("bob: %+v", p)
+	}
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Engine picks moves for a seat with no human behind it. g is the engine's
+// own copy of the position and player is the side to move ("R" or "Y").
+// remaining is the time left on the engine's clock, 0 when the game is
+// untimed. Implementations should give up when ctx is done.
+type Engine interface {
+	ChooseMove(ctx context.Context, g *GameLogic, player string, remaining time.Duration) (int, error)
+}
+
+// EngineFactory builds a fresh engine for one game.
+type EngineFactory func() (Engine, error)
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]EngineFactory{}
+)
+
+// RegisterEngine makes an engine selectable by name (?engine=<name> on /ws).
+// Registering the same name twice replaces the earlier factory.
+func RegisterEngine(name string, f EngineFactory) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	engines[strings.ToLower(name)] = f
+}
+
+// NewEngine builds the engine registered under name.
+func NewEngine(name string) (Engine, error) {
+	enginesMu.RLock()
+	f, ok := engines[strings.ToLower(name)]
+	enginesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %q (have %s)", name, strings.Join(EngineNames(), ", "))
+	}
+	return f()
+}
+
+// HasEngine reports whether name is registered.
+func HasEngine(name string) bool {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	_, ok := engines[strings.ToLower(name)]
+	return ok
+}
+
+func EngineNames() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	names := make([]string, 0, len(engines))
+	for n := range engines {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	for _, d := range []Difficulty{Easy, Medium, Hard, Expert, Perfect} {
+		d := d
+		RegisterEngine(string(d), func() (Engine, error) { return Bot{Level: d}, nil })
+	}
+	RegisterEngine("random", func() (Engine, error) {
+		return &RandomEngine{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+	})
+}
+
+// RandomEngine plays any legal column.
+type RandomEngine struct {
+	rng *rand.Rand
+}
+
+func (e *RandomEngine) ChooseMove(_ context.Context, g *GameLogic, _ string, _ time.Duration) (int, error) {
+	var legal []int
+	for c := 0; c < g.Cols; c++ {
+		if g.ValidColumn(c) {
+			legal = append(legal, c)
+		}
+	}
+	if len(legal) == 0 {
+		return -1, fmt.Errorf("no legal moves")
+	}
+	return legal[e.rng.Intn(len(legal))], nil
+}
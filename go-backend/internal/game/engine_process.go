@@ -0,0 +1,213 @@
+package game
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	engineHandshakeTimeout = 5 * time.Second
+	engineMoveTime         = time.Second
+	engineGrace            = 2 * time.Second // on top of movetime before we give up
+)
+
+// External engines speak a line-based protocol over stdin/stdout, loosely
+// modelled on UCI. Lines from the server:
+//
+//	c4i                                  handshake, sent once at start
+//	newgame <rows> <cols> <connect>      a new board size / rules
+//	isready                              ping, answer "readyok"
+//	position <pos> turn <R|Y>            board in GameLogic.Position form
+//	go movetime <ms>                     think for at most <ms>
+//	stop                                 answer now with the best so far
+//	quit                                 exit
+//
+// Lines from the engine:
+//
+//	id name <name>                       optional, during the handshake
+//	c4iok                                end of the handshake
+//	readyok
+//	info <anything>                      ignored
+//	bestmove <col>                       0-based column
+//
+// Anything else from the engine is ignored.
+
+// ProcessEngine runs an external engine binary and adapts it to Engine.
+// It's not safe for concurrent use; each game gets its own process.
+type ProcessEngine struct {
+	Name string
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan string   // closed when the engine's stdout closes
+	exited  chan struct{} // closed once the process has been reaped
+	variant Variant       // last one sent with newgame
+
+	closeOnce sync.Once
+}
+
+// StartProcessEngine launches path and completes the handshake.
+func StartProcessEngine(path string, args ...string) (*ProcessEngine, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start engine %s: %w", path, err)
+	}
+	e := &ProcessEngine{Name: path, cmd: cmd, stdin: stdin, lines: make(chan string, 16), exited: make(chan struct{})}
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		for sc.Scan() {
+			e.lines <- strings.TrimSpace(sc.Text())
+		}
+		close(e.lines)
+		_ = cmd.Wait()
+		close(e.exited)
+	}()
+
+	if err := e.send("c4i"); err != nil {
+		e.Close()
+		return nil, err
+	}
+	for {
+		line, err := e.readLine(context.Background(), time.Now().Add(engineHandshakeTimeout))
+		if err != nil {
+			e.Close()
+			return nil, fmt.Errorf("engine %s handshake: %w", path, err)
+		}
+		if rest, ok := strings.CutPrefix(line, "id name "); ok {
+			e.Name = rest
+		}
+		if line == "c4iok" {
+			return e, nil
+		}
+	}
+}
+
+func (e *ProcessEngine) send(format string, args ...any) error {
+	_, err := fmt.Fprintf(e.stdin, format+"\n", args...)
+	return err
+}
+
+func (e *ProcessEngine) readLine(ctx context.Context, deadline time.Time) (string, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case line, ok := <-e.lines:
+		if !ok {
+			return "", fmt.Errorf("engine exited")
+		}
+		return line, nil
+	case <-timer.C:
+		return "", fmt.Errorf("engine timed out")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// waitFor reads until a line starting with prefix and returns the rest.
+func (e *ProcessEngine) waitFor(ctx context.Context, prefix string, deadline time.Time) (string, error) {
+	for {
+		line, err := e.readLine(ctx, deadline)
+		if err != nil {
+			return "", err
+		}
+		if line == prefix {
+			return "", nil
+		}
+		if rest, ok := strings.CutPrefix(line, prefix+" "); ok {
+			return rest, nil
+		}
+	}
+}
+
+// ChooseMove pings the engine before every position, so whatever it still
+// had to say about an earlier one (a bestmove after we gave up on it, say)
+// comes before the readyok and is thrown away with it.
+func (e *ProcessEngine) ChooseMove(ctx context.Context, g *GameLogic, player string, remaining time.Duration) (int, error) {
+	v := g.Variant()
+	if v != e.variant {
+		if err := e.send("newgame %d %d %d", v.Rows, v.Cols, v.Connect); err != nil {
+			return -1, err
+		}
+	}
+	if err := e.send("isready"); err != nil {
+		return -1, err
+	}
+	if _, err := e.waitFor(ctx, "readyok", time.Now().Add(engineHandshakeTimeout)); err != nil {
+		return -1, err
+	}
+	e.variant = v
+
+	movetime := engineMoveTime
+	if remaining > 0 && remaining/4 < movetime {
+		movetime = remaining / 4
+	}
+	if err := e.send("position %s turn %s", g.Position(), player); err != nil {
+		return -1, err
+	}
+	if err := e.send("go movetime %d", movetime.Milliseconds()); err != nil {
+		return -1, err
+	}
+	rest, err := e.waitFor(ctx, "bestmove", time.Now().Add(movetime+engineGrace))
+	if err != nil {
+		// make it answer now, so its bestmove lands before the next readyok
+		_ = e.send("stop")
+		return -1, err
+	}
+	col, err := strconv.Atoi(strings.Fields(rest + " x")[0])
+	if err != nil || !g.ValidColumn(col) {
+		return -1, fmt.Errorf("engine played illegal move %q", rest)
+	}
+	return col, nil
+}
+
+// Close asks the engine to quit and kills it if it doesn't.
+func (e *ProcessEngine) Close() error {
+	e.closeOnce.Do(func() {
+		_ = e.send("quit")
+		_ = e.stdin.Close()
+		go func() {
+			for range e.lines {
+				// drain so the reader goroutine can finish
+			}
+		}()
+		go func() {
+			select {
+			case <-e.exited:
+			case <-time.After(engineGrace):
+				log.Printf("engine %s: killed after quit", e.Name)
+				_ = e.cmd.Process.Kill()
+			}
+		}()
+	})
+	return nil
+}
+
+// RegisterProcessEngine registers name to launch command (split on spaces)
+// fresh for every game.
+func RegisterProcessEngine(name, command string) {
+	fields := strings.Fields(command)
+	RegisterEngine(name, func() (Engine, error) {
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("engine %s: empty command", name)
+		}
+		return StartProcessEngine(fields[0], fields[1:]...)
+	})
+}
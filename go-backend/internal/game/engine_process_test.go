@@ -0,0 +1,169 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEngine writes a shell script that speaks the engine protocol, logging
+// what it's sent, and answering every go with reply ("" for never).
+func fakeEngine(t *testing.T, reply string) (path, log string) {
+	t.Helper()
+	dir := t.TempDir()
+	path, log = filepath.Join(dir, "engine.sh"), filepath.Join(dir, "engine.log")
+	answer := ";;"
+	if reply != "" {
+		answer = "echo bestmove " + reply + ";;"
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+while read -r line; do
+  echo "$line" >> %s
+  case "$line" in
+    c4i) echo "id name fake"; echo c4iok;;
+    isready) echo readyok;;
+    go*) %s
+    quit) exit 0;;
+  esac
+done
+`, log, answer)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, log
+}
+
+// startFake starts a fakeEngine and has it quit before its directory goes.
+func startFake(t *testing.T, reply string) (e *ProcessEngine, log string) {
+	t.Helper()
+	path, log := fakeEngine(t, reply)
+	e, err := StartProcessEngine(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		e.Close()
+		<-e.exited
+	})
+	return e, log
+}
+
+func sentLines(t *testing.T, log string) []string {
+	t.Helper()
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestProcessEngine(t *testing.T) {
+	e, log := startFake(t, "2")
+	if e.Name != "fake" {
+		t.Errorf("Name = %q, want the id name", e.Name)
+	}
+
+	g := playMoves(t, Standard, []int{3})
+	for i := 0; i < 2; i++ {
+		col, err := e.ChooseMove(context.Background(), g, "Y", 0)
+		if err != nil || col != 2 {
+			t.Fatalf("move %d: %d, %v", i+1, col, err)
+		}
+	}
+	sent := strings.Join(sentLines(t, log), "\n")
+	if n := strings.Count(sent, "newgame 6 7 4"); n != 1 {
+		t.Errorf("newgame sent %d times:\n%s", n, sent)
+	}
+	if !strings.Contains(sent, "position "+g.Position()+" turn Y\ngo movetime 1000") {
+		t.Errorf("no position and go:\n%s", sent)
+	}
+
+	// a clock running low cuts the move time
+	if _, err := e.ChooseMove(context.Background(), g, "Y", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if lines := sentLines(t, log); lines[len(lines)-1] != "go movetime 500" {
+		t.Errorf("last line %q, want go movetime 500", lines[len(lines)-1])
+	}
+}
+
+func TestProcessEngineIllegalMove(t *testing.T) {
+	e, _ := startFake(t, "7")
+	if _, err := e.ChooseMove(context.Background(), NewGame(), "R", 0); err == nil || !strings.Contains(err.Error(), "illegal") {
+		t.Errorf("column 7 on a 7-column board: %v", err)
+	}
+}
+
+func TestProcessEngineStopsWhenCancelled(t *testing.T) {
+	e, log := startFake(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := e.ChooseMove(ctx, NewGame(), "R", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ChooseMove: %v, want the context's error", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("took %v to give up", took)
+	}
+	e.Close()
+	<-e.exited
+	if lines := sentLines(t, log); lines[len(lines)-2] != "stop" || lines[len(lines)-1] != "quit" {
+		t.Errorf("wanted stop then quit, got %q", lines)
+	}
+}
+
+// A bestmove that turns up after ChooseMove gave up on it isn't taken as
+// the answer to the next position.
+func TestProcessEngineDropsALateAnswer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slow.sh")
+	script := `#!/bin/sh
+slow=1
+while read -r line; do
+  case "$line" in
+    c4i) echo "id name slow"; echo c4iok;;
+    isready) echo readyok;;
+    stop) echo bestmove 5;;
+    go*) if [ $slow = 0 ]; then echo bestmove 2; fi; slow=0;;
+    quit) exit 0;;
+  esac
+done
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	e, err := StartProcessEngine(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		e.Close()
+		<-e.exited
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := e.ChooseMove(ctx, NewGame(), "R", 0); err == nil {
+		t.Fatal("the first move didn't time out")
+	}
+	if col, err := e.ChooseMove(context.Background(), NewGame(), "R", 0); err != nil || col != 2 {
+		t.Errorf("second move: %d, %v; want 2, not the late answer to the first", col, err)
+	}
+}
+
+func TestStartProcessEngineHandshake(t *testing.T) {
+	if _, err := StartProcessEngine(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("started an engine that doesn't exist")
+	}
+	path := filepath.Join(t.TempDir(), "mute.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nread -r line\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartProcessEngine(path); err == nil || !strings.Contains(err.Error(), "handshake") {
+		t.Errorf("engine that exits at once: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineRegistry(t *testing.T) {
+	for _, name := range []string{"easy", "medium", "hard", "expert", "perfect", "random"} {
+		if !slices.Contains(EngineNames(), name) {
+			t.Errorf("%q isn't registered: %v", name, EngineNames())
+		}
+	}
+	if !HasEngine("Hard") {
+		t.Error("names aren't case-insensitive")
+	}
+	if _, err := NewEngine("nope"); err == nil || !strings.Contains(err.Error(), "random") {
+		t.Errorf("NewEngine of an unknown name: %v, want one listing the engines", err)
+	}
+
+	RegisterEngine("Test-Fixed", func() (Engine, error) { return fixedEngine(2), nil })
+	defer func() {
+		enginesMu.Lock()
+		delete(engines, "test-fixed")
+		enginesMu.Unlock()
+	}()
+	e, err := NewEngine("test-fixed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if col, err := e.ChooseMove(context.Background(), NewGame(), "R", 0); col != 2 || err != nil {
+		t.Errorf("registered engine played %d, %v", col, err)
+	}
+}
+
+type fixedEngine int
+
+func (e fixedEngine) ChooseMove(context.Context, *GameLogic, string, time.Duration) (int, error) {
+	return int(e), nil
+}
+
+func TestRandomEnginePlaysLegalMoves(t *testing.T) {
+	e, err := NewEngine("random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only column 4 is left
+	g := playMoves(t, Variant{Rows: 1, Cols: 5, Connect: 4}, []int{0, 1, 2, 3})
+	for i := 0; i < 20; i++ {
+		if col, err := e.ChooseMove(context.Background(), g, "R", 0); col != 4 || err != nil {
+			t.Fatalf("played %d, %v; want 4", col, err)
+		}
+	}
+	g.DropDisc(4, "R")
+	if _, err := e.ChooseMove(context.Background(), g, "Y", 0); err == nil {
+		t.Error("a move on a full board")
+	}
+}
+
+func TestBotKeepsToItsClock(t *testing.T) {
+	// expert would think for seconds on an empty board; with 400ms left it
+	// gets a quarter of that
+	start := time.Now()
+	col, err := Bot{Level: Expert}.ChooseMove(context.Background(), NewGame(), "R", 400*time.Millisecond)
+	if took := time.Since(start); took > 300*time.Millisecond {
+		t.Errorf("took %v with 400ms on the clock", took)
+	}
+	if err != nil || !NewGame().ValidColumn(col) {
+		t.Errorf("played %d, %v", col, err)
+	}
+}
+
+func TestSearchContextStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res := SearchContext(ctx, NewGame(), "R", 0, 0)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("took %v after the context was done", took)
+	}
+	if res.Col < 0 {
+		t.Errorf("no move: %+v", res)
+	}
+}
@@ -1,93 +1,148 @@
 package game
 
+// GameLogic keeps the position as bitboards: one mask per side plus the
+// next free bit of every column. Each column takes Rows+1 bits (the extra
+// bit is a sentinel so shifts never bleed into the neighbouring column),
+// bit 0 of a column being its bottom cell.
 type GameLogic struct {
-	Rows  int
-	Cols  int
-	Board [][]*string // nil or "R"/"Y"
+	Rows    int
+	Cols    int
+	Connect int
+
+	discs  [2]bitset    // [0] = "R", [1] = "Y"
+	height [maxCols]int // next free bit index per column
+	moves  int
 }
 
+// NewGame returns an empty Standard board.
 func NewGame() *GameLogic {
-	rows, cols := 6, 7
-	b := make([][]*string, rows)
-	for r := 0; r < rows; r++ {
-		b[r] = make([]*string, cols)
+	g, _ := NewVariantGame(Standard)
+	return g
+}
+
+func NewVariantGame(v Variant) (*GameLogic, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	g := &GameLogic{Rows: v.Rows, Cols: v.Cols, Connect: v.Connect}
+	for c := 0; c < g.Cols; c++ {
+		g.height[c] = c * (g.Rows + 1)
 	}
-	return &GameLogic{Rows: rows, Cols: cols, Board: b}
+	return g, nil
 }
 
+func (g *GameLogic) Variant() Variant {
+	return Variant{Rows: g.Rows, Cols: g.Cols, Connect: g.Connect}
+}
+
+// Clone is a plain value copy; no board allocation.
 func (g *GameLogic) Clone() *GameLogic {
-	n := NewGame()
-	for r := range g.Board {
-		for c := range g.Board[r] {
-			if g.Board[r][c] != nil {
-				val := *g.Board[r][c]
-				n.Board[r][c] = &val
-			}
-		}
+	n := *g
+	return &n
+}
+
+func sideIndex(player string) int {
+	switch player {
+	case "R":
+		return 0
+	case "Y":
+		return 1
 	}
-	return n
+	return -1
+}
+
+func (g *GameLogic) topBit(col int) int {
+	return col*(g.Rows+1) + g.Rows - 1
 }
 
 func (g *GameLogic) ValidColumn(col int) bool {
-	return col >= 0 && col < g.Cols && g.Board[0][col] == nil
+	return col >= 0 && col < g.Cols && g.height[col] <= g.topBit(col)
 }
 
+// DropDisc returns the row the disc landed on, counted from the top like
+// the wire board.
 func (g *GameLogic) DropDisc(col int, player string) (row int, ok bool) {
-	if !g.ValidColumn(col) { return -1, false }
-	for r := g.Rows - 1; r >= 0; r-- {
-		if g.Board[r][col] == nil {
-			val := player
-			g.Board[r][col] = &val
-			return r, true
-		}
+	side := sideIndex(player)
+	if side < 0 || !g.ValidColumn(col) {
+		return -1, false
 	}
-	return -1, false
+	bit := g.height[col]
+	g.play(col, side)
+	return g.Rows - 1 - (bit - col*(g.Rows+1)), true
+}
+
+// play and undo are the allocation-free make/unmake used by the search;
+// side is 0 for "R" and 1 for "Y" and the column must be valid.
+func (g *GameLogic) play(col, side int) {
+	g.discs[side] = g.discs[side].or(bitAt(g.height[col]))
+	g.height[col]++
+	g.moves++
+}
+
+func (g *GameLogic) undo(col int) {
+	g.height[col]--
+	g.moves--
+	mask := bitAt(g.height[col]).not()
+	g.discs[0] = g.discs[0].and(mask)
+	g.discs[1] = g.discs[1].and(mask)
 }
 
 func (g *GameLogic) CheckWinner(p string) bool {
-	b := g.Board; R, C := g.Rows, g.Cols
-	// horizontal
-	for r := 0; r < R; r++ {
-		for c := 0; c <= C-4; c++ {
-			if b[r][c] != nil && b[r][c+1] != nil && b[r][c+2] != nil && b[r][c+3] != nil &&
-				*b[r][c] == p && *b[r][c+1] == p && *b[r][c+2] == p && *b[r][c+3] == p {
-				return true
-			}
-		}
+	side := sideIndex(p)
+	if side < 0 {
+		return false
 	}
-	// vertical
-	for c := 0; c < C; c++ {
-		for r := 0; r <= R-4; r++ {
-			if b[r][c] != nil && b[r+1][c] != nil && b[r+2][c] != nil && b[r+3][c] != nil &&
-				*b[r][c] == p && *b[r+1][c] == p && *b[r+2][c] == p && *b[r+3][c] == p {
-				return true
-			}
-		}
-	}
-	// diag down-right
-	for r := 0; r <= R-4; r++ {
-		for c := 0; c <= C-4; c++ {
-			if b[r][c] != nil && b[r+1][c+1] != nil && b[r+2][c+2] != nil && b[r+3][c+3] != nil &&
-				*b[r][c] == p && *b[r+1][c+1] == p && *b[r+2][c+2] == p && *b[r+3][c+3] == p {
-				return true
-			}
+	return g.wins(side)
+}
+
+func (g *GameLogic) wins(side int) bool {
+	b := g.discs[side]
+	h := uint(g.Rows + 1)
+	// vertical, horizontal, diag down-right, diag up-right
+	for _, d := range []uint{1, h, h - 1, h + 1} {
+		m := b
+		for i := 1; i < g.Connect && !m.isZero(); i++ {
+			m = m.and(b.shr(uint(i) * d))
 		}
-	}
-	// diag up-right
-	for r := 3; r < R; r++ {
-		for c := 0; c <= C-4; c++ {
-			if b[r][c] != nil && b[r-1][c+1] != nil && b[r-2][c+2] != nil && b[r-3][c+3] != nil &&
-				*b[r][c] == p && *b[r-1][c+1] == p && *b[r-2][c+2] == p && *b[r-3][c+3] == p {
-				return true
-			}
+		if !m.isZero() {
+			return true
 		}
 	}
 	return false
 }
 
 func (g *GameLogic) IsFull() bool {
-	for c := 0; c < g.Cols; c++ {
-		if g.Board[0][c] == nil { return false }
+	return g.moves == g.Rows*g.Cols
+}
+
+// MoveCount is the number of discs on the board.
+func (g *GameLogic) MoveCount() int {
+	return g.moves
+}
+
+// Cell returns "R", "Y" or "" for the given row (from the top) and column.
+func (g *GameLogic) Cell(row, col int) string {
+	i := col*(g.Rows+1) + g.Rows - 1 - row
+	switch {
+	case g.discs[0].has(i):
+		return "R"
+	case g.discs[1].has(i):
+		return "Y"
+	}
+	return ""
+}
+
+// Board converts to the wire/storage shape: rows top to bottom, nil for
+// empty cells, "R"/"Y" otherwise.
+func (g *GameLogic) Board() [][]*string {
+	b := make([][]*string, g.Rows)
+	for r := 0; r < g.Rows; r++ {
+		b[r] = make([]*string, g.Cols)
+		for c := 0; c < g.Cols; c++ {
+			if v := g.Cell(r, c); v != "" {
+				b[r][c] = &v
+			}
+		}
 	}
-	return true
+	return b
 }
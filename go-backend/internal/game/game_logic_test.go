@@ -0,0 +1,161 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// grid is the obvious board: cells[row][col], row 0 at the top.
+type grid struct {
+	rows, cols, connect int
+	cells               [][]string
+}
+
+func newGrid(rows, cols, connect int) *grid {
+	g := &grid{rows: rows, cols: cols, connect: connect, cells: make([][]string, rows)}
+	for r := range g.cells {
+		g.cells[r] = make([]string, cols)
+	}
+	return g
+}
+
+func (g *grid) drop(col int, p string) (row int, ok bool) {
+	if col < 0 || col >= g.cols {
+		return -1, false
+	}
+	for r := g.rows - 1; r >= 0; r-- {
+		if g.cells[r][col] == "" {
+			g.cells[r][col] = p
+			return r, true
+		}
+	}
+	return -1, false
+}
+
+// wins walks every cell in every direction looking for connect in a row.
+func (g *grid) wins(p string) bool {
+	for r := 0; r < g.rows; r++ {
+		for c := 0; c < g.cols; c++ {
+			for _, d := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+				n := 0
+				for rr, cc := r, c; rr >= 0 && rr < g.rows && cc >= 0 && cc < g.cols && g.cells[rr][cc] == p; rr, cc = rr+d[0], cc+d[1] {
+					n++
+				}
+				if n >= g.connect {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestDropDiscAndCheckWinnerMatchBruteForce(t *testing.T) {
+	variants := []Variant{
+		Standard,
+		{Rows: 4, Cols: 4, Connect: 3},
+		{Rows: 5, Cols: 4, Connect: 4},
+		{Rows: 6, Cols: 7, Connect: 5},
+		{Rows: 1, Cols: 16, Connect: 4},
+		{Rows: 16, Cols: 7, Connect: 4},
+		{Rows: 8, Cols: 9, Connect: 5},
+		{Rows: 9, Cols: 12, Connect: 6},
+		{Rows: 3, Cols: 3, Connect: 2},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, v := range variants {
+		t.Run(v.String(), func(t *testing.T) {
+			for game := 0; game < 200; game++ {
+				g, err := NewVariantGame(v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := newGrid(v.Rows, v.Cols, v.Connect)
+				for ply := 0; !g.IsFull(); ply++ {
+					p := sideToMove(ply)
+					col := rng.Intn(v.Cols+2) - 1 // now and then off the board
+					wantRow, wantOK := want.drop(col, p)
+					row, ok := g.DropDisc(col, p)
+					if row != wantRow || ok != wantOK {
+						t.Fatalf("game %d ply %d: DropDisc(%d, %s) = %d, %v; want %d, %v", game, ply, col, p, row, ok, wantRow, wantOK)
+					}
+					if !ok {
+						ply--
+						continue
+					}
+					if g.MoveCount() != ply+1 {
+						t.Fatalf("game %d: MoveCount() = %d after %d discs", game, g.MoveCount(), ply+1)
+					}
+					for _, side := range []string{"R", "Y"} {
+						if got, w := g.CheckWinner(side), want.wins(side); got != w {
+							t.Fatalf("game %d ply %d: CheckWinner(%s) = %v, want %v\n%v", game, ply, side, got, w, want.cells)
+						}
+					}
+					if want.wins(p) {
+						break
+					}
+				}
+				for r := 0; r < v.Rows; r++ {
+					for c := 0; c < v.Cols; c++ {
+						if got := g.Cell(r, c); got != want.cells[r][c] {
+							t.Fatalf("game %d: Cell(%d, %d) = %q, want %q", game, r, c, got, want.cells[r][c])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCheckWinner(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     Variant
+		moves []int // R first
+		want  string
+	}{
+		{"horizontal", Standard, []int{0, 0, 1, 1, 2, 2, 3}, "R"},
+		{"vertical", Standard, []int{0, 1, 0, 1, 0, 1, 6, 1}, "Y"},
+		{"rising diagonal", Standard, []int{0, 1, 1, 2, 2, 3, 2, 3, 3, 6, 3}, "R"},
+		{"falling diagonal", Standard, []int{6, 5, 5, 4, 4, 3, 4, 3, 3, 0, 3}, "R"},
+		// the top of one column and the bottom of the next aren't a line
+		{"no wrap up a column", Standard, []int{0, 6, 0, 6, 0, 6, 1, 0, 6, 0, 6, 0}, ""},
+		{"three is enough for c3", Variant{Rows: 4, Cols: 4, Connect: 3}, []int{0, 0, 1, 1, 2}, "R"},
+		{"four isn't enough for c5", Variant{Rows: 6, Cols: 7, Connect: 5}, []int{0, 0, 1, 1, 2, 2, 3}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewVariantGame(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, col := range tt.moves {
+				if _, ok := g.DropDisc(col, sideToMove(i)); !ok {
+					t.Fatalf("move %d: column %d is full", i+1, col)
+				}
+			}
+			got := ""
+			for _, p := range []string{"R", "Y"} {
+				if g.CheckWinner(p) {
+					got += p
+				}
+			}
+			if got != tt.want {
+				t.Errorf("winner = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	g := NewGame()
+	g.DropDisc(3, "R")
+	c := g.Clone()
+	c.DropDisc(3, "Y")
+	if g.Cell(4, 3) != "" || g.MoveCount() != 1 {
+		t.Fatal("dropping on a clone changed the original")
+	}
+	if c.Cell(5, 3) != "R" || c.Cell(4, 3) != "Y" {
+		t.Fatalf("clone has %q under %q in column 3", c.Cell(5, 3), c.Cell(4, 3))
+	}
+}
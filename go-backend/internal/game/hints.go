@@ -0,0 +1,55 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// hintLevel is the bot level hints are asked of.
+const hintLevel = Hard
+
+// hintsLeft is how many more hints side may ask for in st.
+func (m *Manager) hintsLeft(st *state, side string) int {
+	return max(m.HintsPerGame-st.hints[side], 0)
+}
+
+// hint asks the engine for a move for side and sends it to them alone; runs
+// on st's goroutine. The hint is spent straight away, and the first one
+// takes the game out of the ratings.
+func (m *Manager) hint(st *state, side string) *protocol.Error {
+	switch {
+	case st.over:
+		return reject(protocol.CodeGameOver, "the game is over")
+	case m.HintsPerGame <= 0:
+		return reject(protocol.CodeNoHints, "hints are off on this server")
+	case st.turn != side:
+		return reject(protocol.CodeNotYourTurn, "hints are for your own move")
+	case m.hintsLeft(st, side) == 0:
+		return reject(protocol.CodeNoHints, fmt.Sprintf("you've used all %d hints for this game", m.HintsPerGame))
+	}
+	st.hints[side]++
+	if m.rates(st) {
+		m.broadcast(st, protocol.Info{Message: st.seat(side).username + " took a hint, so this game won't be rated"})
+	}
+	st.rated = false
+	m.checkpoint(st)
+
+	pos, ply := st.game.Clone(), len(st.moves)
+	var left time.Duration // 0 = untimed, the bot uses its own limit
+	if st.clock.tc.Enabled() {
+		left = st.clock.remaining(side, st.turn)
+	}
+	go func() {
+		col, err := Bot{Level: hintLevel}.ChooseMove(st.ctx, pos, side, left)
+		st.post(func() {
+			// no use once they've moved or the game is over
+			if err != nil || st.over || len(st.moves) != ply {
+				return
+			}
+			st.seat(side).conn.send(protocol.Suggestion{Col: col, HintsLeft: m.hintsLeft(st, side)})
+		})
+	}()
+	return nil
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+func TestHints(t *testing.T) {
+	m := testManager(time.Minute)
+	m.HintsPerGame = 2
+	dial := serve(t, m)
+	alice := dial(as("alice"))
+	expect(t, alice, "queued")
+	bob := dial(as("bob"))
+	if msg := expect(t, alice, "start"); msg["hints"] != float64(2) || msg["rated"] != true {
+		t.Fatalf("start: %v", msg)
+	}
+	gameID := expect(t, bob, "start")["gameId"].(string)
+
+	send(t, bob, "hint", "seq", 1)
+	if msg := expect(t, bob, "error"); msg["code"] != "not_your_turn" || msg["seq"] != float64(1) {
+		t.Errorf("hint out of turn: %v", msg)
+	}
+
+	// the first hint takes the game out of the ratings, and both hear so
+	send(t, alice, "hint")
+	if msg := expect(t, bob, "info"); !strings.Contains(msg["message"].(string), "won't be rated") {
+		t.Errorf("info: %v", msg)
+	}
+	expect(t, alice, "info")
+	if msg := expect(t, alice, "suggestion"); msg["hintsLeft"] != float64(1) {
+		t.Errorf("first suggestion: %v", msg)
+	}
+	send(t, alice, "hint")
+	if msg := expect(t, alice, "suggestion"); msg["hintsLeft"] != float64(0) {
+		t.Errorf("second suggestion: %v", msg)
+	}
+	send(t, alice, "hint", "seq", 3)
+	if msg := expect(t, alice, "error"); msg["code"] != "no_hints" || msg["seq"] != float64(3) {
+		t.Errorf("a third hint: %v", msg)
+	}
+
+	play(t, alice, bob, 0, 1, 0, 1, 0, 1, 0)
+	expect(t, alice, "gameOver")
+	var g models.GameDoc
+	waitFor(t, "the game to be stored", func() bool {
+		var err error
+		g, err = m.Store.GetGame(context.Background(), gameID)
+		return err == nil
+	})
+	if g.Rated || g.Hints != 2 {
+		t.Errorf("stored as rated %v with %d hints, want unrated with 2", g.Rated, g.Hints)
+	}
+	if a := storedPlayer(t, m, "alice"); a.RatedGames != 0 || a.Rating != 1500 {
+		t.Errorf("alice was rated: %+v", a)
+	}
+}
+
+func TestHintsOff(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, _, _ := pairUp(t, serve(t, m), "")
+	send(t, alice, "hint")
+	if msg := expect(t, alice, "error"); msg["code"] != "no_hints" {
+		t.Errorf("hint with hints off: %v", msg)
+	}
+}
@@ -0,0 +1,27 @@
+package game
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/yourname/fourinarow/internal/auth"
+)
+
+var errNoIdentity = errors.New("log in for a token, or play as a guest with ?guest=<name>")
+
+// identify says who is opening /ws: an account holder with a session token
+// (Authorization: Bearer or ?token=), or a guest with ?guest=<name>, who
+// plays as "guest-<name>" and only in unrated games.
+func (m *Manager) identify(r *http.Request) (string, error) {
+	if token := auth.BearerToken(r); token != "" {
+		return m.Sessions.Verify(token)
+	}
+	name := r.URL.Query().Get("guest")
+	if name == "" {
+		return "", errNoIdentity
+	}
+	if err := auth.ValidUsername(name); err != nil {
+		return "", err
+	}
+	return auth.GuestPrefix + name, nil
+}
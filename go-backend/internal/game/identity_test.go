@@ -0,0 +1,48 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/auth"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+func TestGuestsPlayUnrated(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+	alice := dial("guest=alice")
+	expect(t, alice, "queued")
+	bob := dial("guest=bob")
+	if msg := expect(t, alice, "start"); msg["you"] != "guest-alice" || msg["opponent"] != "guest-bob" {
+		t.Errorf("start: %v", msg)
+	}
+	expect(t, bob, "start")
+
+	play(t, alice, bob, 0, 1, 0, 1, 0, 1, 0)
+	if msg := expect(t, bob, "gameOver"); msg["result"] != "guest-alice wins" {
+		t.Errorf("gameOver: %v", msg)
+	}
+	waitFor(t, "the game to be stored", func() bool { return len(m.LiveGames()) == 0 })
+	for _, name := range []string{"alice", "guest-alice"} {
+		if _, err := m.Store.GetPlayer(context.Background(), name); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s stored: %v", name, err)
+		}
+	}
+}
+
+func TestWSNeedsAnIdentity(t *testing.T) {
+	m := testManager(time.Minute)
+	other, _ := auth.Tokens{Secret: []byte("another server"), TTL: time.Hour}.Issue("alice")
+	for _, query := range []string{"", "username=alice", "token=nonsense", "token=" + other, "guest=a", "guest=bad%20name"} {
+		rec := httptest.NewRecorder()
+		m.HandleWS(rec, httptest.NewRequest(http.MethodGet, "/ws?"+query, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("?%s: HTTP %d, want 401", query, rec.Code)
+		}
+	}
+}
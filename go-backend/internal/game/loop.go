@@ -0,0 +1,58 @@
+package game
+
+// Each game runs on its own goroutine, which owns its state: the board,
+// clock, seats, spectators and so on are only touched from there. Readers,
+// timers and bot searches hand it work through post. The Manager's mutex
+// only covers the registry (queue, rooms, active, userToGame) and st.next,
+// which readers follow to find the game to post to.
+//
+// Lock order: a game may take m.mu, but nothing holding m.mu may post to or
+// wait on a game, since post blocks while the inbox is full.
+
+const inboxSize = 32
+
+// post queues fn to run on st's goroutine. It reports false, without
+// running fn, if the game has already wound down.
+func (st *state) post(fn func()) bool {
+	select {
+	case st.inbox <- fn:
+		return true
+	case <-st.done:
+		return false
+	}
+}
+
+// call runs fn on st's goroutine and waits for it; false if the game wound
+// down first. Don't use it from a game or while holding m.mu.
+func (st *state) call(fn func()) bool {
+	ran := make(chan struct{})
+	if !st.post(func() { fn(); close(ran) }) {
+		return false
+	}
+	select {
+	case <-ran:
+		return true
+	case <-st.done:
+		select {
+		case <-ran:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// run is the game's goroutine. It keeps going after the game ends, for
+// rematch requests, until a rematch starts or both players are gone, or
+// until Shutdown parks it.
+func (m *Manager) run(st *state) {
+	defer close(st.done)
+	m.checkpoint(st)
+	for !st.settled() {
+		(<-st.inbox)()
+	}
+}
+
+func (st *state) settled() bool {
+	return st.parked || st.over && (st.next != nil || st.p1.conn == nil && st.p2.conn == nil)
+}
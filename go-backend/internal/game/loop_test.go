@@ -0,0 +1,114 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Many games at once, some with rematches, while the lobby keeps listing
+// them; run with -race to check who touches what.
+func TestGamesRunSideBySide(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.LiveGames()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	t.Run("games", func(t *testing.T) {
+		for i := 0; i < 12; i++ {
+			red := dial(as(fmt.Sprintf("red%d", i)))
+			expect(t, red, "queued")
+			yellow := dial(as(fmt.Sprintf("yellow%d", i)))
+			expect(t, red, "start")
+			expect(t, yellow, "start")
+
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				play(t, red, yellow, 0, 1, 0, 1, 0, 1, 0)
+				if msg := expect(t, red, "gameOver"); msg["reason"] != "connect" {
+					t.Errorf("gameOver: %v", msg)
+				}
+				expect(t, yellow, "gameOver")
+				if i%3 == 0 {
+					send(t, red, "rematch")
+					expect(t, yellow, "rematchOffered")
+					send(t, yellow, "rematch")
+					expect(t, red, "start")
+					expect(t, yellow, "start")
+					send(t, red, "resign")
+					expect(t, red, "gameOver")
+					expect(t, yellow, "gameOver")
+				}
+				_ = red.Close()
+				_ = yellow.Close()
+			})
+		}
+	})
+
+	waitFor(t, "every game to wind down", func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.active) == 0 && len(m.userToGame) == 0
+	})
+}
+
+func TestRejoinThenAbandon(t *testing.T) {
+	m := testManager(time.Minute)
+	m.RejoinGrace = 300 * time.Millisecond
+	dial := serve(t, m)
+	alice, bob, gameID := pairUp(t, dial, "")
+
+	_ = alice.Close()
+	expect(t, bob, "info")
+	alice = dial("resume=" + m.resumeToken(gameID, "R"))
+	expect(t, alice, "rejoined")
+	time.Sleep(2 * m.RejoinGrace) // the first grace timer mustn't end it
+	if games := m.LiveGames(); len(games) != 1 {
+		t.Fatalf("live games after a rejoin: %+v", games)
+	}
+
+	_ = bob.Close()
+	expect(t, alice, "info")
+	if msg := expect(t, alice, "gameOver"); msg["reason"] != "abandoned" || msg["result"] != "alice wins" {
+		t.Errorf("gameOver: %v", msg)
+	}
+}
+
+// Once a game's goroutine is gone, calling into it reports so rather than
+// blocking.
+func TestPostAfterTheGameWindsDown(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, gameID := pairUp(t, serve(t, m), "")
+	m.mu.Lock()
+	st := m.active[gameID]
+	m.mu.Unlock()
+
+	ran := false
+	if !st.call(func() { ran = true }) || !ran {
+		t.Fatal("call on a live game didn't run")
+	}
+	send(t, alice, "resign")
+	expect(t, bob, "gameOver")
+	_ = alice.Close()
+	_ = bob.Close()
+	select {
+	case <-st.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the game's goroutine outlived both players")
+	}
+	if st.call(func() { t.Error("ran on a finished game") }) {
+		t.Error("call on a finished game reported it ran")
+	}
+}
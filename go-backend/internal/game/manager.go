@@ -2,35 +2,56 @@ package game
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/auth"
 	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/protocol"
+	"github.com/yourname/fourinarow/internal/rating"
 	"github.com/yourname/fourinarow/internal/store"
 	"github.com/yourname/fourinarow/internal/util"
 )
 
 type Manager struct {
-	Store           *store.MongoStore
-	MatchBotAfter   time.Duration
-	RejoinGrace     time.Duration
-	BotDelay        time.Duration
-
-	upgrader websocket.Upgrader
-
-	mu          sync.Mutex
-	waiting     *waitingPlayer
-	active      map[string]*state // gameId -> state
-	userToGame  map[string]*userRef
+	Store             store.Store
+	MatchBotAfter     time.Duration
+	RejoinGrace       time.Duration
+	BotDelay          time.Duration
+	RoomExpiry        time.Duration
+	MatchRatingWindow int           // max rating gap for a match, widens while waiting; 0 = FIFO
+	BotRating         float64       // bot games are rated against this; 0 leaves them unrated
+	TokenSecret       []byte        // signs resume tokens; random unless set, so tokens die with the process
+	Sessions          auth.Tokens   // checks the login tokens /ws is opened with
+	AnnotateMove      time.Duration // engine time per position when annotating finished games; 0 = off
+	HintsPerGame      int           // hints each player may ask for per game; 0 = off
+
+	upgrader    websocket.Upgrader
+	annotations chan annotateJob // see annotate.go
+
+	// mu guards the registry below; each game's own state belongs to the
+	// game's goroutine, see loop.go
+	mu         sync.Mutex
+	queue      map[queueKey][]*queueEntry // FIFO per settings
+	rooms      map[string]*room           // code -> private room
+	active     map[string]*state          // gameId -> state
+	userToGame map[string]*userRef
+	closing    bool // Shutdown was called, no new games
 }
 
-type waitingPlayer struct {
-	username string
-	conn     *websocket.Conn
-	timer    *time.Timer
+// joinOpts are the game settings a client asks for on /ws.
+type joinOpts struct {
+	variant Variant
+	engine  string // registered engine name, used if we fall back to the bot
+	room    string // roomCreate or a room code; empty for public matchmaking
+	rated   bool
+	rating  int // the player's current rating, for pairing
+	tc      TimeControl
 }
 
 type userRef struct {
@@ -40,9 +61,10 @@ type userRef struct {
 
 type playerConn struct {
 	username string
-	conn     *websocket.Conn
+	conn     *client
 	side     string
-	bot      *Bot
+	bot      Engine // nil for humans
+	reading  bool   // a lobbyLoop already reads this conn, don't start readLoop
 }
 
 type state struct {
@@ -50,287 +72,589 @@ type state struct {
 	p1       playerConn // R
 	p2       playerConn // Y (or BOT)
 	game     *GameLogic
+	rated    bool
 	turn     string
 	startAt  time.Time
 	moves    []models.Move
+	ctx      context.Context // cancelled when the game ends, stops engine searches
+	cancel   context.CancelFunc
 	rejoinP1 *time.Timer
 	rejoinP2 *time.Timer
+	opts     joinOpts
+	over     bool  // decided, finishGame is on its way
+	clock    clock // unused when untimed, see clock.go
+	// draw offers and rematches, see actions.go
+	drawOffer string // side with an open draw offer
+	rematch   string // side that asked for a rematch
+	next      *state // the rematch, once both agreed; guarded by m.mu
+	parked    bool   // checkpointed by Shutdown, to be picked up after the restart
+	// hints taken per side, see hints.go
+	hints map[string]int
+	// read-only watchers, see spectate.go
+	spectators map[*client]struct{}
+	// the game's goroutine, see loop.go
+	inbox chan func()
+	done  chan struct{}
 }
 
-func NewManager(store *store.MongoStore, matchBotMs, rejoinMs, botDelayMs int) *Manager {
-	return &Manager{
-		Store:         store,
-		MatchBotAfter: time.Duration(matchBotMs) * time.Millisecond,
-		RejoinGrace:   time.Duration(rejoinMs) * time.Millisecond,
-		BotDelay:      time.Duration(botDelayMs) * time.Millisecond,
+func NewManager(store store.Store, matchBotMs, rejoinMs, botDelayMs, roomExpiryMs, ratingWindow int) *Manager {
+	secret := newTokenSecret()
+	m := &Manager{
+		Store:             store,
+		MatchBotAfter:     time.Duration(matchBotMs) * time.Millisecond,
+		RejoinGrace:       time.Duration(rejoinMs) * time.Millisecond,
+		BotDelay:          time.Duration(botDelayMs) * time.Millisecond,
+		RoomExpiry:        time.Duration(roomExpiryMs) * time.Millisecond,
+		MatchRatingWindow: ratingWindow,
+		TokenSecret:       secret,
+		Sessions:          auth.Tokens{Secret: secret, TTL: auth.DefaultTTL},
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
-		active:     make(map[string]*state),
-		userToGame: make(map[string]*userRef),
+		queue:       make(map[queueKey][]*queueEntry),
+		rooms:       make(map[string]*room),
+		active:      make(map[string]*state),
+		userToGame:  make(map[string]*userRef),
+		annotations: make(chan annotateJob, annotateQueue),
 	}
+	go m.annotateLoop()
+	if ratingWindow > 0 {
+		go m.pairLoop()
+	}
+	return m
 }
 
 func (m *Manager) HandleWS(w http.ResponseWriter, r *http.Request) {
-	username := r.URL.Query().Get("username")
-	gameID := r.URL.Query().Get("gameId")
-
-	if username == "" {
-		http.Error(w, "username required", http.StatusBadRequest)
+	version, err := protocol.Negotiate(r.URL.Query().Get("v"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	conn, err := m.upgrader.Upgrade(w, r, nil)
-	if err != nil { return }
-
-	_ = m.Store.EnsurePlayer(r.Context(), username)
-
-	if gameID != "" {
-		m.tryRejoin(conn, username, gameID)
+	m.mu.Lock()
+	closing := m.closing
+	m.mu.Unlock()
+	if closing {
+		http.Error(w, "server is restarting", http.StatusServiceUnavailable)
 		return
 	}
-	m.enqueueOrMatch(conn, username)
-}
-
-func (m *Manager) enqueueOrMatch(conn *websocket.Conn, username string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
 
-	if ref, ok := m.userToGame[username]; ok {
-		// already in a game; rejoin it
-		m.mu.Unlock()
-		m.tryRejoin(conn, username, ref.gameID)
-		m.mu.Lock()
+	// spectators don't need a username
+	if watch := r.URL.Query().Get("spectate"); watch != "" {
+		ws, err := m.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := newClient(ws)
+		conn.send(protocol.Hello{Version: version})
+		m.spectate(conn, watch)
 		return
 	}
-
-	if m.waiting != nil && m.waiting.username != username {
-		wp := m.waiting
-		if wp.timer != nil { wp.timer.Stop() }
-		m.waiting = nil
-		m.startGame(playerConn{username: wp.username, conn: wp.conn, side: "R"},
-			playerConn{username: username, conn: conn, side: "Y"})
+	// replays of finished games are open to anyone too
+	if id := r.URL.Query().Get("replay"); id != "" {
+		speed, err := ParseSpeed(r.URL.Query().Get("speed"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ws, err := m.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := newClient(ws)
+		conn.send(protocol.Hello{Version: version})
+		m.replay(conn, id, speed)
 		return
 	}
-
-	// set waiting + bot fallback
-	timer := time.AfterFunc(m.MatchBotAfter, func() {
-		m.mu.Lock()
-		defer m.mu.Unlock()
-		if m.waiting != nil && m.waiting.username == username {
-			p1 := playerConn{username: username, conn: conn, side: "R"}
-			p2 := playerConn{username: "BOT", conn: nil, side: "Y", bot: &Bot{Symbol: "Y"}}
-			m.waiting = nil
-			m.startGame(p1, p2)
+	// back into a game after a dropped connection; the token says whose seat
+	if token := r.URL.Query().Get("resume"); token != "" {
+		ws, err := m.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
 		}
-	})
-	m.waiting = &waitingPlayer{username: username, conn: conn, timer: timer}
-	sendJSON(conn, map[string]any{"type": "queued", "message": "Waiting for opponent..."})
-}
+		conn := newClient(ws)
+		conn.send(protocol.Hello{Version: version})
+		m.resume(conn, token)
+		return
+	}
+	username, err := m.identify(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	guest := auth.IsGuest(username)
+	var opts joinOpts
+	if opts.variant, err = ParseVariant(r.URL.Query().Get("rows"), r.URL.Query().Get("cols"), r.URL.Query().Get("connect")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// ?engine= picks any registered engine; ?difficulty= is shorthand for
+	// the built-in bot levels
+	opts.engine = r.URL.Query().Get("engine")
+	if opts.engine == "" {
+		d, err := ParseDifficulty(r.URL.Query().Get("difficulty"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.engine = string(d)
+	}
+	if !HasEngine(opts.engine) {
+		http.Error(w, "unknown engine "+opts.engine, http.StatusBadRequest)
+		return
+	}
+	opts.room = r.URL.Query().Get("room")
+	opts.rated = r.URL.Query().Get("rated") != "false" && !guest
+	if opts.tc, err = ParseTimeControl(r.URL.Query().Get("time")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ws, err := m.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn := newClient(ws)
+	conn.send(protocol.Hello{Version: version})
+
+	// guests are never stored, so they have no record to add to
+	opts.rating = int(rating.DefaultRating)
+	if !guest {
+		_ = m.Store.EnsurePlayer(r.Context(), username)
+		if p, err := m.Store.GetPlayer(r.Context(), username); err == nil {
+			opts.rating = int(p.Rating)
+		}
+	}
 
-func (m *Manager) startGame(p1, p2 playerConn) {
-	st := &state{
-		gameID:  util.NewID(10),
-		p1:      p1,
-		p2:      p2,
-		game:    NewGame(),
-		turn:    "R",
-		startAt: time.Now(),
+	switch {
+	case opts.room == roomCreate:
+		m.createRoom(conn, username, opts)
+	case opts.room != "":
+		m.joinRoom(conn, username, opts.room)
+	default:
+		m.enqueue(conn, username, opts)
 	}
-	m.active[st.gameID] = st
-	m.userToGame[p1.username] = &userRef{gameID: st.gameID, side: "R"}
-	m.userToGame[p2.username] = &userRef{gameID: st.gameID, side: "Y"}
+}
 
-	startPayload := func(pc playerConn, opp string) map[string]any {
-		return map[string]any{
-			"type":     "start",
-			"gameId":   st.gameID,
-			"color":    pc.side,
-			"opponent": opp,
-			"board":    st.game.Board,
-			"turn":     st.turn,
+// startGame seats p1 (R) and p2 (Y) and starts the game's goroutine;
+// caller holds m.mu.
+func (m *Manager) startGame(p1, p2 playerConn, opts joinOpts) *state {
+	variant := opts.variant
+	g, _ := NewVariantGame(variant) // validated in HandleWS
+	st := m.newState(util.NewID(10), p1, p2, g, opts)
+
+	m.startClock(st)
+	startPayload := func(pc playerConn, opp string) protocol.Start {
+		return protocol.Start{
+			GameID:   st.gameID,
+			Color:    pc.side,
+			You:      pc.username,
+			Opponent: opp,
+			Board:    st.game.Board(),
+			Turn:     st.turn,
+			Rows:     variant.Rows,
+			Cols:     variant.Cols,
+			Connect:  variant.Connect,
+			Clock:    st.clock.payload(st.turn),
+			Resume:   m.resumeToken(st.gameID, pc.side),
+			Rated:    m.rates(st),
+			Hints:    m.hintsLeft(st, pc.side),
 		}
 	}
-	sendJSON(p1.conn, startPayload(p1, p2.username))
+	p1.conn.send(startPayload(p1, p2.username))
 	if p2.conn != nil {
-		sendJSON(p2.conn, startPayload(p2, p1.username))
+		p2.conn.send(startPayload(p2, p1.username))
 	}
 
 	// readers
-	go m.readLoop(st, p1)
-	if p2.conn != nil {
+	if p1.conn != nil && !p1.reading {
+		go m.readLoop(st, p1)
+	}
+	if p2.conn != nil && !p2.reading {
 		go m.readLoop(st, p2)
 	}
+	if p1.bot != nil {
+		m.botMove(st)
+	}
+	go m.run(st)
+	return st
 }
 
-func (m *Manager) tryRejoin(conn *websocket.Conn, username, gameID string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// newState registers a game at move one, R to play; caller holds m.mu.
+// Nothing runs it yet.
+func (m *Manager) newState(gameID string, p1, p2 playerConn, g *GameLogic, opts joinOpts) *state {
+	st := &state{
+		gameID:  gameID,
+		p1:      p1,
+		p2:      p2,
+		game:    g,
+		rated:   opts.rated && !auth.IsGuest(p1.username) && !auth.IsGuest(p2.username),
+		turn:    "R",
+		startAt: time.Now(),
+		opts:    opts,
 
-	st, ok := m.active[gameID]
-	if !ok {
-		sendJSON(conn, map[string]any{"type": "error", "message": "game not found or finished"})
-		m.mu.Unlock()
-		m.enqueueOrMatch(conn, username)
-		m.mu.Lock()
-		return
+		clock:      clock{tc: opts.tc},
+		spectators: make(map[*client]struct{}),
+		hints:      make(map[string]int),
+		inbox:      make(chan func(), inboxSize),
+		done:       make(chan struct{}),
 	}
-	isP1 := st.p1.username == username
-	isP2 := st.p2.username == username
-	if !isP1 && !isP2 {
-		sendJSON(conn, map[string]any{"type": "error", "message": "this game does not belong to you"})
-		m.mu.Unlock()
-		m.enqueueOrMatch(conn, username)
-		m.mu.Lock()
+	st.ctx, st.cancel = context.WithCancel(context.Background())
+	m.active[st.gameID] = st
+	m.userToGame[p1.username] = &userRef{gameID: st.gameID, side: "R"}
+	m.userToGame[p2.username] = &userRef{gameID: st.gameID, side: "Y"}
+	return st
+}
+
+// rejoin puts conn in the seat on side; runs on st's goroutine.
+func (m *Manager) rejoin(st *state, conn *client, side string) {
+	if st.over {
+		// it ended while the request was on its way
+		conn.send(errGameEnded)
+		conn.close()
 		return
 	}
-
-	if isP1 {
+	// the token holder takes the seat over from any connection still in it
+	if old := st.seat(side).conn; old != nil {
+		old.close()
+	}
+	if side == "R" {
 		st.p1.conn = conn
-		if st.rejoinP1 != nil { st.rejoinP1.Stop(); st.rejoinP1 = nil }
+		if st.rejoinP1 != nil {
+			st.rejoinP1.Stop()
+			st.rejoinP1 = nil
+		}
 	} else {
 		st.p2.conn = conn
-		if st.rejoinP2 != nil { st.rejoinP2.Stop(); st.rejoinP2 = nil }
+		if st.rejoinP2 != nil {
+			st.rejoinP2.Stop()
+			st.rejoinP2 = nil
+		}
 	}
-	sendJSON(conn, map[string]any{
-		"type": "rejoined", "gameId": st.gameID,
-		"color": func() string { if isP1 { return "R" } else { return "Y" } }(),
-		"opponent": func() string { if isP1 { return st.p2.username } else { return st.p1.username } }(),
-		"board": st.game.Board, "turn": st.turn,
+	m.unpause(st)
+	conn.send(protocol.Rejoined{
+		GameID:   st.gameID,
+		Color:    side,
+		You:      st.seat(side).username,
+		Opponent: st.seat(opponent(side)).username,
+		Board:    st.game.Board(), Turn: st.turn,
+		Rows: st.game.Rows, Cols: st.game.Cols, Connect: st.game.Connect,
+		Clock:  st.clock.payload(st.turn),
+		Resume: m.resumeToken(st.gameID, side),
+		Rated:  m.rates(st),
+		Hints:  m.hintsLeft(st, side),
 	})
-	go m.readLoop(st, func() playerConn {
-		if isP1 { return st.p1 }
-		return st.p2
-	}())
+	go m.readLoop(st, *st.seat(side))
 }
 
 func (m *Manager) readLoop(st *state, pc playerConn) {
 	conn := pc.conn
-	if conn == nil { return }
+	if conn == nil {
+		return
+	}
 	defer func() {
 		// disconnection -> start rejoin timer
-		m.onDisconnect(st, pc.side)
+		m.mu.Lock()
+		cur, side := st.latest(pc.username)
+		m.mu.Unlock()
+		cur.post(func() { m.onDisconnect(cur, side, conn) })
 	}()
 
 	for {
-		_, msg, err := conn.ReadMessage()
-		if err != nil { return }
-		var in struct {
-			Type string `json:"type"`
-			Col  int    `json:"col"`
-		}
-		if err := json.Unmarshal(msg, &in); err != nil { continue }
-		if in.Type == "move" {
-			m.applyMove(st, pc.side, in.Col)
+		msg, err := conn.read()
+		if err != nil {
+			return
 		}
+		m.mu.Lock()
+		cur, side := st.latest(pc.username)
+		m.mu.Unlock()
+		cur.post(func() { m.handleMessage(cur, side, msg) })
 	}
 }
 
-func (m *Manager) applyMove(st *state, side string, col int) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if st.turn != side { return }
+// latest follows rematches from st to the game username is playing now and
+// returns their side in it; caller holds m.mu.
+func (st *state) latest(username string) (*state, string) {
+	for st.next != nil {
+		st = st.next
+	}
+	if st.p1.username == username {
+		return st, "R"
+	}
+	return st, "Y"
+}
+
+// seat is the player on side.
+func (st *state) seat(side string) *playerConn {
+	if side == "R" {
+		return &st.p1
+	}
+	return &st.p2
+}
 
-	row, ok := st.game.DropDisc(col, side)
-	if !ok { return }
+// handleMessage acts on a message from the player on side; runs on st's
+// goroutine.
+func (m *Manager) handleMessage(st *state, side string, msg []byte) {
+	in, err := protocol.DecodeClient(msg)
+	if err != nil {
+		st.seat(side).conn.send(protocol.AsError(err))
+		return
+	}
+	var rej *protocol.Error
+	switch in := in.(type) {
+	case *protocol.Move:
+		rej = m.applyMove(st, side, in.Col)
+	case *protocol.Resign:
+		rej = m.resign(st, side)
+	case *protocol.OfferDraw:
+		rej = m.offerDraw(st, side)
+	case *protocol.AcceptDraw:
+		rej = m.acceptDraw(st, side)
+	case *protocol.DeclineDraw:
+		rej = m.declineDraw(st, side)
+	case *protocol.Rematch:
+		rej = m.requestRematch(st, side)
+	case *protocol.Hint:
+		rej = m.hint(st, side)
+	case *protocol.Pause, *protocol.Play, *protocol.Step, *protocol.Seek:
+		rej = reject(protocol.CodeNotReplay, "that only works in a replay")
+	}
+	if rej != nil {
+		rej.Seq = in.Sequence()
+		st.seat(side).conn.send(*rej)
+	}
+}
+
+// reject builds the error for an action that can't be done.
+func reject(code, msg string) *protocol.Error {
+	return &protocol.Error{Code: code, Message: msg}
+}
+
+// applyMove plays col for side; runs on st's goroutine.
+func (m *Manager) applyMove(st *state, side string, col int) *protocol.Error {
+	switch {
+	case st.over:
+		return reject(protocol.CodeGameOver, "the game is over")
+	case st.turn != side:
+		return reject(protocol.CodeNotYourTurn, "it's not your turn")
+	case col < 0 || col >= st.game.Cols:
+		return reject(protocol.CodeInvalidColumn, fmt.Sprintf("column %d is off the board (0-%d)", col, st.game.Cols-1))
+	case !st.game.ValidColumn(col):
+		return reject(protocol.CodeColumnFull, fmt.Sprintf("column %d is full", col))
+	}
+	if !m.punchClock(st, side) {
+		return reject(protocol.CodeGameOver, "your time ran out")
+	}
+
+	row, _ := st.game.DropDisc(col, side)
 
 	st.moves = append(st.moves, models.Move{Player: side, Col: col, Row: row, At: time.Now()})
+	if st.drawOffer != "" && st.drawOffer != side {
+		st.drawOffer = "" // moving instead of answering declines it
+	}
 
 	nextTurn := map[string]string{"R": "Y", "Y": "R"}[side]
-	update := map[string]any{"type": "update", "move": map[string]any{"row": row, "col": col, "player": side}, "board": st.game.Board, "turn": nextTurn}
-	sendJSON(st.p1.conn, update)
-	sendJSON(st.p2.conn, update)
+	m.broadcast(st, protocol.Update{
+		Move:  protocol.Played{Row: row, Col: col, Player: side},
+		Board: st.game.Board(), Turn: nextTurn, Clock: st.clock.payload(nextTurn),
+	})
 
 	win := st.game.CheckWinner(side)
 	full := st.game.IsFull()
 	if win || full {
-		go m.finishGame(st, func() string {
-			if win {
-				if side == "R" { return st.p1.username }
-				return st.p2.username
-			}
-			return "Draw"
-		}())
-		return
+		if win {
+			m.endGame(st, st.seat(side).username, "connect")
+		} else {
+			m.endGame(st, "Draw", "boardFull")
+		}
+		return nil
 	}
 
 	st.turn = nextTurn
+	m.checkpoint(st)
 
-	// bot
-	if st.p2.bot != nil && st.turn == "Y" {
-		time.AfterFunc(m.BotDelay, func() {
-			col := st.p2.bot.ChooseMove(st.game)
-			m.applyMove(st, "Y", col)
-		})
+	if st.seat(st.turn).bot != nil {
+		m.botMove(st)
 	}
+	return nil
 }
 
-func (m *Manager) onDisconnect(st *state, side string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// botMove has the bot seated at st.turn reply after BotDelay; runs on st's
+// goroutine. The search itself runs on the timer's goroutine, on a copy of
+// the board, so the game stays responsive while the engine thinks.
+func (m *Manager) botMove(st *state) {
+	side := st.turn
+	bot := st.seat(side).bot
+	pos := st.game.Clone()
+	var left time.Duration // 0 = untimed, the engine uses its own limit
+	if st.clock.tc.Enabled() {
+		left = st.clock.left[side] - m.BotDelay
+		if left <= 0 {
+			left = time.Millisecond
+		}
+	}
+	time.AfterFunc(m.BotDelay, func() {
+		col, err := bot.ChooseMove(st.ctx, pos, side, left)
+		st.post(func() {
+			if err == nil {
+				rej := m.applyMove(st, side, col)
+				if rej == nil || rej.Code == protocol.CodeGameOver {
+					return
+				}
+				err = rej
+			}
+			if !st.over {
+				// crashed, timed out or played an illegal move: the bot forfeits
+				log.Printf("game %s: engine error: %v", st.gameID, err)
+				human := st.seat(opponent(side))
+				human.conn.send(protocol.Info{Message: "Bot engine failed, you win by forfeit"})
+				m.endGame(st, human.username, "engineFailure")
+			}
+		})
+	})
+}
 
-	timer := time.AfterFunc(m.RejoinGrace, func() {
-		m.mu.Lock()
-		defer m.mu.Unlock()
-		winner := st.p1.username
-		if side == "R" { winner = st.p2.username }
-		go m.finishGame(st, "Forfeit:"+winner)
+// onDisconnect handles conn, the socket on side, going away; runs on st's
+// goroutine.
+func (m *Manager) onDisconnect(st *state, side string, conn *client) {
+	pc := st.seat(side)
+	// a rejoin already replaced it
+	if pc.conn != conn {
+		return
+	}
+	pc.conn = nil
+	if st.over {
+		// nothing to rejoin, just stop offering a rematch to this socket
+		if st.rematch == side {
+			st.rematch = ""
+		}
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(m.RejoinGrace, func() {
+		st.post(func() {
+			// they may have come back while this was queued
+			if side == "R" && st.rejoinP1 != timer || side == "Y" && st.rejoinP2 != timer {
+				return
+			}
+			if opp := st.seat(opponent(side)); opp.bot != nil || opp.conn != nil {
+				m.endGame(st, opp.username, "abandoned")
+				return
+			}
+			// neither of them came back, e.g. after a restart: nobody won
+			m.endGame(st, "", "aborted")
+		})
 	})
+	st.seat(opponent(side)).conn.send(protocol.Info{Message: "Opponent disconnected, waiting 30s to rejoin..."})
 	if side == "R" {
 		st.rejoinP1 = timer
-		if st.p2.conn != nil {
-			sendJSON(st.p2.conn, map[string]any{"type": "info", "message": "Opponent disconnected, waiting 30s to rejoin..."})
-		}
 	} else {
 		st.rejoinP2 = timer
-		if st.p1.conn != nil {
-			sendJSON(st.p1.conn, map[string]any{"type": "info", "message": "Opponent disconnected, waiting 30s to rejoin..."})
-		}
 	}
 }
 
-func (m *Manager) finishGame(st *state, winnerLabel string) {
+// endGame marks st decided, stops its clock and finishes it; runs on st's
+// goroutine. Only the first call for a game counts.
+// winner is a username, "Draw", or "" for a game called off without a
+// result; reason says how it ended (connect, boardFull, resign, drawAgreed,
+// timeout, abandoned, aborted, engineFailure).
+func (m *Manager) endGame(st *state, winner, reason string) {
+	if st.over {
+		return
+	}
+	st.over = true
+	st.drawOffer = ""
+	st.clock.stop()
+	m.finishGame(st, winner, reason)
+}
+
+// rates reports whether st's result will move ratings.
+func (m *Manager) rates(st *state) bool {
+	return st.rated && (st.p1.bot == nil && st.p2.bot == nil || m.BotRating > 0)
+}
+
+// finishGame persists st, tells everyone and takes it off the registry.
+// An aborted game (no winner) is dropped instead: it isn't stored, rated or
+// counted. The game's goroutine carries on for rematch requests; see run.
+func (m *Manager) finishGame(st *state, winner, reason string) {
+	st.cancel()
+	for _, b := range []Engine{st.p1.bot, st.p2.bot} {
+		if c, ok := b.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
 	// persist + leaderboard
 	duration := int(time.Since(st.startAt).Seconds())
-	isDraw := winnerLabel == "Draw"
-	isForfeit := len(winnerLabel) > 8 && winnerLabel[:8] == "Forfeit:"
-	winner := winnerLabel
-	if isForfeit {
-		winner = winnerLabel[8:]
-	}
-
-	_ = m.Store.InsertGame(context.Background(), models.GameDoc{
-		GameID:     st.gameID,
-		Player1:    st.p1.username,
-		Player2:    st.p2.username,
-		Winner:     winner,
-		Duration:   duration,
-		FinalBoard: st.game.Board,
-		Moves:      st.moves,
-	})
+	isDraw := winner == "Draw"
+	aborted := winner == ""
+
+	rated := !aborted && m.rates(st)
+	var ratings []models.RatingChange
+	if rated {
+		score1 := 0.5
+		if !isDraw {
+			score1 = 0
+			if winner == st.p1.username {
+				score1 = 1
+			}
+		}
+		var err error
+		if ratings, err = m.Store.RateGame(context.Background(), st.p1.username, st.p2.username, score1, m.BotRating); err != nil {
+			log.Printf("game %s: rating update failed: %v", st.gameID, err)
+		}
+	}
 
-	if isDraw {
-		_ = m.Store.IncDraws(context.Background(), []string{st.p1.username, st.p2.username})
-	} else {
-		loser := st.p1.username
-		if winner == st.p1.username { loser = st.p2.username }
-		_ = m.Store.IncWinLoss(context.Background(), winner, loser)
+	_ = m.Store.DeleteActiveGame(context.Background(), st.gameID)
+	if !aborted {
+		_ = m.Store.InsertGame(context.Background(), models.GameDoc{
+			GameID:      st.gameID,
+			Player1:     st.p1.username,
+			Player2:     st.p2.username,
+			Winner:      winner,
+			Reason:      reason,
+			Duration:    duration,
+			FinalBoard:  st.game.Board(),
+			Moves:       st.moves,
+			Rated:       rated,
+			Ratings:     ratings,
+			TimeControl: st.clock.tc.String(),
+			Rows:        st.game.Rows,
+			Cols:        st.game.Cols,
+			Connect:     st.game.Connect,
+			Hints:       st.hints["R"] + st.hints["Y"],
+		})
+		m.queueAnnotation(st.gameID, st.game.Variant(), st.moves)
+
+		if isDraw {
+			_ = m.Store.IncDraws(context.Background(), []string{st.p1.username, st.p2.username})
+		} else {
+			loser := st.p1.username
+			if winner == st.p1.username {
+				loser = st.p2.username
+			}
+			_ = m.Store.IncWinLoss(context.Background(), winner, loser)
+		}
 	}
 
-	sendJSON(st.p1.conn, map[string]any{"type": "gameOver", "result": func() string {
-		if isDraw { return "Draw" }
-		return winner + " wins"
-	}()})
-	sendJSON(st.p2.conn, map[string]any{"type": "gameOver", "result": func() string {
-		if isDraw { return "Draw" }
+	m.broadcast(st, protocol.GameOver{Reason: reason, Result: func() string {
+		if isDraw {
+			return "Draw"
+		}
+		if aborted {
+			return "Aborted"
+		}
 		return winner + " wins"
 	}()})
+	for conn := range st.spectators {
+		conn.close()
+	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	delete(m.active, st.gameID)
-	delete(m.userToGame, st.p1.username)
-	delete(m.userToGame, st.p2.username)
-}
-
-func sendJSON(conn *websocket.Conn, v any) {
-	if conn == nil { return }
-	_ = conn.WriteJSON(v)
+	// a quick rematch may already have moved them on to a new game
+	for _, u := range []string{st.p1.username, st.p2.username} {
+		if ref := m.userToGame[u]; ref != nil && ref.gameID == st.gameID {
+			delete(m.userToGame, u)
+		}
+	}
 }
@@ -0,0 +1,102 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+// waitFor polls cond until it holds or a couple of seconds pass.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func storedPlayer(t *testing.T, m *Manager, name string) models.Player {
+	t.Helper()
+	p, err := m.Store.GetPlayer(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestFinishedGameIsRatedAndCounted(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		rated bool
+	}{{"", true}, {"&rated=false", false}} {
+		m := testManager(time.Minute)
+		alice, bob, _ := pairUp(t, serve(t, m), tt.query)
+
+		play(t, alice, bob, 0, 1, 0, 1, 0, 1, 0)
+		if msg := expect(t, alice, "gameOver"); msg["result"] != "alice wins" {
+			t.Errorf("gameOver: %v", msg)
+		}
+		expect(t, bob, "gameOver")
+
+		waitFor(t, "the result to be stored", func() bool { return storedPlayer(t, m, "bob").Losses == 1 })
+		a, b := storedPlayer(t, m, "alice"), storedPlayer(t, m, "bob")
+		if a.Wins != 1 {
+			t.Errorf("alice: %+v", a)
+		}
+		if rated := a.RatedGames == 1 && a.Rating > 1500 && b.Rating < 1500; rated != tt.rated {
+			t.Errorf("?%s: alice %+v, bob %+v; want rated %v", tt.query, a, b, tt.rated)
+		}
+	}
+}
+
+func TestHelloAndBadFrames(t *testing.T) {
+	dial := serve(t, testManager(time.Minute))
+	alice := dial(as("alice") + "&v=1")
+	if msg := expect(t, alice, "hello"); msg["version"] != float64(1) {
+		t.Errorf("hello: %v", msg)
+	}
+	bob := dial(as("bob"))
+	expect(t, alice, "start")
+	expect(t, bob, "start")
+
+	for frame, code := range map[string]string{
+		"not json":                  "bad_message",
+		`{"type":"move"}`:           "bad_message",
+		`{"type":"teleport"}`:       "unknown_type",
+		`{"type":"move","col":"x"}`: "bad_message",
+	} {
+		if err := alice.WriteMessage(websocket.TextMessage, []byte(frame)); err != nil {
+			t.Fatal(err)
+		}
+		if msg := expect(t, alice, "error"); msg["code"] != code {
+			t.Errorf("%s: %v, want %s", frame, msg, code)
+		}
+	}
+	// and the game goes on
+	play(t, alice, bob, 3)
+}
+
+func TestRejectedMovesEchoTheirSeq(t *testing.T) {
+	alice, bob, _ := pairUp(t, serve(t, testManager(time.Minute)), "")
+	play(t, alice, bob, 0, 0, 0, 0, 0, 0)
+	for _, tt := range []struct {
+		who  *websocket.Conn
+		col  int
+		code string
+	}{
+		{bob, 1, "not_your_turn"},
+		{alice, 0, "column_full"},
+		{alice, 7, "invalid_column"},
+		{alice, -1, "invalid_column"},
+	} {
+		send(t, tt.who, "move", "col", tt.col, "seq", 40+tt.col)
+		if msg := expect(t, tt.who, "error"); msg["code"] != tt.code || msg["seq"] != float64(40+tt.col) {
+			t.Errorf("move %d: %v, want %s", tt.col, msg, tt.code)
+		}
+	}
+	play(t, alice, bob, 1)
+}
@@ -0,0 +1,288 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+// A game record in c4n notation is a few headers, one per line, followed
+// by the moves:
+//
+//	[Game "k3J9xQ"]
+//	[Red "alice"]
+//	[Yellow "bob"]
+//	[Variant "6x7c4"]
+//	[Date "2024-05-01T18:04:05Z"]
+//	[TimeControl "60+2"]
+//	[Times "1.2 0.8 3.05 2"]
+//	[Result "R"]
+//	[Termination "connect"]
+//
+//	4453443
+//
+// Moves are the columns played, R first, one character each: '1'-'9' then
+// 'a'-'g' for columns 10-16 on wide boards. Whitespace in the moves is
+// ignored. Result is "R", "Y", "draw" or "*" for a game that wasn't
+// finished; Times is the seconds each move took. Every header is optional
+// and unknown ones are skipped; Variant defaults to Standard.
+
+const columnChars = "123456789abcdefg"
+
+// Record is one game in c4n.
+type Record struct {
+	GameID      string
+	Red, Yellow string
+	Variant     Variant
+	Date        time.Time // when the game started
+	TimeControl string
+	Result      string // "R", "Y", "draw" or "*"
+	Termination string // connect, resign, timeout, ...; see Manager.endGame
+	Moves       []int  // 0-based columns
+	Times       []time.Duration
+}
+
+// String encodes r as c4n.
+func (r Record) String() string {
+	var sb strings.Builder
+	header := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "[%s %s]\n", key, strconv.Quote(value))
+		}
+	}
+	header("Game", r.GameID)
+	header("Red", r.Red)
+	header("Yellow", r.Yellow)
+	header("Variant", r.Variant.String())
+	if !r.Date.IsZero() {
+		header("Date", r.Date.UTC().Format(time.RFC3339))
+	}
+	header("TimeControl", r.TimeControl)
+	if len(r.Times) > 0 {
+		secs := make([]string, len(r.Times))
+		for i, d := range r.Times {
+			secs[i] = strconv.FormatFloat(float64(d.Milliseconds())/1000, 'f', -1, 64)
+		}
+		header("Times", strings.Join(secs, " "))
+	}
+	header("Result", r.Result)
+	header("Termination", r.Termination)
+	sb.WriteByte('\n')
+	for _, c := range r.Moves {
+		sb.WriteByte(columnChars[c])
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// ParseRecord reads c4n and replays the moves to check them. A missing
+// Result is filled in from the board if the moves decide the game.
+func ParseRecord(s string) (Record, error) {
+	r := Record{Variant: Standard}
+	var moves strings.Builder
+	for n, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "[") {
+			moves.WriteString(line)
+			continue
+		}
+		key, raw, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"), " ")
+		value, err := strconv.Unquote(strings.TrimSpace(raw))
+		if !ok || err != nil {
+			return r, fmt.Errorf("line %d: want [Key \"value\"]", n+1)
+		}
+		if err := r.setHeader(key, value); err != nil {
+			return r, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	for _, ch := range strings.Join(strings.Fields(moves.String()), "") {
+		c := strings.IndexRune(columnChars, ch)
+		if c < 0 || c >= r.Variant.Cols {
+			return r, fmt.Errorf("move %d: %q isn't a column on a %s board", len(r.Moves)+1, ch, r.Variant)
+		}
+		r.Moves = append(r.Moves, c)
+	}
+	if len(r.Times) > 0 && len(r.Times) != len(r.Moves) {
+		return r, fmt.Errorf("%d times for %d moves", len(r.Times), len(r.Moves))
+	}
+	_, decided, err := r.replay()
+	if err != nil {
+		return r, err
+	}
+	switch {
+	case decided != "" && r.Result == "":
+		r.Result = decided
+	case decided != "" && r.Result != decided:
+		return r, fmt.Errorf("result %q doesn't match the moves, which give %q", r.Result, decided)
+	case r.Result == "":
+		r.Result = "*"
+	}
+	return r, nil
+}
+
+func (r *Record) setHeader(key, value string) error {
+	var err error
+	switch key {
+	case "Game":
+		r.GameID = value
+	case "Red":
+		r.Red = value
+	case "Yellow":
+		r.Yellow = value
+	case "Variant":
+		var v Variant
+		if _, err := fmt.Sscanf(value, "%dx%dc%d", &v.Rows, &v.Cols, &v.Connect); err != nil {
+			return fmt.Errorf("variant %q, want like 6x7c4", value)
+		}
+		if err := v.Validate(); err != nil {
+			return err
+		}
+		r.Variant = v
+	case "Date":
+		if r.Date, err = time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("date %q isn't RFC 3339", value)
+		}
+	case "TimeControl":
+		if _, err := ParseTimeControl(value); err != nil {
+			return err
+		}
+		r.TimeControl = value
+	case "Times":
+		for _, f := range strings.Fields(value) {
+			secs, err := strconv.ParseFloat(f, 64)
+			if err != nil || secs < 0 {
+				return fmt.Errorf("time %q isn't a number of seconds", f)
+			}
+			r.Times = append(r.Times, time.Duration(secs*float64(time.Second)))
+		}
+	case "Result":
+		switch value {
+		case "R", "Y", "draw", "*":
+		default:
+			return fmt.Errorf("result %q, want R, Y, draw or *", value)
+		}
+		r.Result = value
+	case "Termination":
+		r.Termination = value
+	}
+	return nil
+}
+
+// replay plays r's moves on a fresh board. decided is "R" or "Y" if a move
+// connected, "draw" if the board filled up, and empty otherwise.
+func (r Record) replay() (g *GameLogic, decided string, err error) {
+	if g, err = NewVariantGame(r.Variant); err != nil {
+		return nil, "", err
+	}
+	for i, col := range r.Moves {
+		if decided != "" {
+			return nil, "", fmt.Errorf("move %d: the game was already over", i+1)
+		}
+		p := sideToMove(i)
+		if _, ok := g.DropDisc(col, p); !ok {
+			return nil, "", fmt.Errorf("move %d: column %d is full", i+1, col+1)
+		}
+		switch {
+		case g.CheckWinner(p):
+			decided = p
+		case g.IsFull():
+			decided = "draw"
+		}
+	}
+	return g, decided, nil
+}
+
+func sideToMove(ply int) string {
+	if ply%2 == 0 {
+		return "R"
+	}
+	return "Y"
+}
+
+// RecordFromDoc is the c4n for a stored game.
+func RecordFromDoc(d models.GameDoc) Record {
+	r := Record{
+		GameID:      d.GameID,
+		Red:         d.Player1,
+		Yellow:      d.Player2,
+		Variant:     Variant{Rows: d.Rows, Cols: d.Cols, Connect: d.Connect},
+		Date:        d.CreatedAt.Add(-time.Duration(d.Duration) * time.Second),
+		TimeControl: d.TimeControl,
+		Termination: d.Reason,
+		Result:      "*",
+	}
+	if r.Variant.Connect == 0 && len(d.FinalBoard) > 0 {
+		// stored before games kept their variant; only the size shows
+		r.Variant = Variant{Rows: len(d.FinalBoard), Cols: len(d.FinalBoard[0]), Connect: Standard.Connect}
+	}
+	switch d.Winner {
+	case d.Player1:
+		r.Result = "R"
+	case d.Player2:
+		r.Result = "Y"
+	case "Draw":
+		r.Result = "draw"
+	}
+	timed := len(d.Moves) > 0
+	last := r.Date
+	for _, m := range d.Moves {
+		r.Moves = append(r.Moves, m.Col)
+		timed = timed && !m.At.IsZero()
+		r.Times = append(r.Times, max(m.At.Sub(last), 0))
+		last = m.At
+	}
+	if !timed {
+		r.Times = nil
+	}
+	return r
+}
+
+// Doc replays r into the stored-game shape, as if it had been played here.
+// Nothing is stored; CreatedAt is when it ended, as with a stored game, so
+// RecordFromDoc gives r back.
+func (r Record) Doc() (models.GameDoc, error) {
+	g, err := NewVariantGame(r.Variant)
+	if err != nil {
+		return models.GameDoc{}, err
+	}
+	d := models.GameDoc{
+		GameID:      r.GameID,
+		Player1:     r.Red,
+		Player2:     r.Yellow,
+		Reason:      r.Termination,
+		TimeControl: r.TimeControl,
+		Rows:        r.Variant.Rows,
+		Cols:        r.Variant.Cols,
+		Connect:     r.Variant.Connect,
+		Moves:       []models.Move{},
+	}
+	at := r.Date
+	for i, col := range r.Moves {
+		p := sideToMove(i)
+		row, ok := g.DropDisc(col, p)
+		if !ok {
+			return d, fmt.Errorf("move %d: column %d is full", i+1, col+1)
+		}
+		m := models.Move{Player: p, Col: col, Row: row}
+		if len(r.Times) == len(r.Moves) {
+			at = at.Add(r.Times[i])
+			m.At = at
+		}
+		d.Moves = append(d.Moves, m)
+	}
+	d.Duration = int(at.Sub(r.Date).Seconds())
+	d.CreatedAt = at
+	d.FinalBoard = g.Board()
+	switch r.Result {
+	case "R":
+		d.Winner = r.Red
+	case "Y":
+		d.Winner = r.Yellow
+	case "draw":
+		d.Winner = "Draw"
+	}
+	return d, nil
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	date := time.Date(2024, 5, 1, 18, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		rec  Record
+	}{
+		{"finished", Record{
+			GameID: "k3J9xQ", Red: "alice", Yellow: "bob", Variant: Standard, Date: date,
+			TimeControl: "60+2", Result: "R", Termination: "connect",
+			Moves: []int{3, 3, 2, 4, 1, 5, 0},
+			Times: []time.Duration{1200 * time.Millisecond, 800 * time.Millisecond, 3050 * time.Millisecond, 2 * time.Second, 0, time.Second, 1500 * time.Millisecond},
+		}},
+		{"resigned, untimed", Record{
+			Red: "alice", Yellow: "BOT", Variant: Standard, Date: date,
+			Result: "Y", Termination: "resign", Moves: []int{3, 3},
+		}},
+		{"wide board", Record{
+			GameID: "w1", Red: "a", Yellow: "b", Variant: Variant{Rows: 5, Cols: 16, Connect: 5}, Date: date,
+			Result: "*", Moves: []int{15, 9, 10, 0, 12},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := tt.rec.String()
+			got, err := ParseRecord(text)
+			if err != nil {
+				t.Fatalf("ParseRecord: %v\n%s", err, text)
+			}
+			if !reflect.DeepEqual(got, tt.rec) {
+				t.Fatalf("parsed back as\n%+v\nwant\n%+v\nfrom\n%s", got, tt.rec, text)
+			}
+		})
+	}
+}
+
+// A stored game exported as c4n and imported again comes back the same.
+func TestExportImport(t *testing.T) {
+	start := time.Date(2024, 5, 1, 18, 4, 5, 0, time.UTC)
+	g, err := PlayMoves(Standard, []int{3, 3, 2, 4, 1, 5, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moves []models.Move
+	at := start
+	probe := NewGame()
+	for i, col := range []int{3, 3, 2, 4, 1, 5, 0} {
+		at = at.Add(time.Duration(i+1) * time.Second)
+		row, _ := probe.DropDisc(col, sideToMove(i))
+		moves = append(moves, models.Move{Player: sideToMove(i), Col: col, Row: row, At: at})
+	}
+	stored := models.GameDoc{
+		GameID: "k3J9xQ", Player1: "alice", Player2: "bob", Winner: "alice", Reason: "connect",
+		Duration: int(at.Sub(start).Seconds()), FinalBoard: g.Board(), Moves: moves,
+		TimeControl: "60+2", Rows: 6, Cols: 7, Connect: 4,
+		CreatedAt: at, // stored when it finished
+	}
+
+	text := RecordFromDoc(stored).String()
+	if !strings.Contains(text, "\n4435261\n") {
+		t.Errorf("moves not in the export:\n%s", text)
+	}
+	rec, err := ParseRecord(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rec.Doc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, stored) {
+		t.Fatalf("imported as\n%+v\nwant\n%+v", got, stored)
+	}
+}
+
+func TestParseRecordErrors(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"column off the board", "[Variant \"6x4c4\"]\n125", "isn't a column"},
+		{"full column", "1111111", "column 1 is full"},
+		{"moves after the end", "12121212", "already over"},
+		{"wrong result", "[Result \"Y\"]\n1212121", "doesn't match"},
+		{"bad header", "[Red alice]\n4", "want [Key \"value\"]"},
+		{"times don't match", "[Times \"1 2\"]\n4", "2 times for 1 moves"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRecord(tt.text)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/protocol"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// Games in progress are checkpointed to the store after every move. On a
+// restart Restore loads them back and their players reconnect with their
+// resume tokens as if they had dropped; Shutdown parks them for that on the
+// way down. The tokens only verify after the restart if TokenSecret is
+// fixed; with a random one the restored games wait out the rejoin grace
+// and are called off.
+
+// checkpoint saves st; runs on st's goroutine.
+func (m *Manager) checkpoint(st *state) {
+	g := models.ActiveGame{
+		GameID:      st.gameID,
+		Player1:     st.p1.username,
+		Player2:     st.p2.username,
+		Rows:        st.game.Rows,
+		Cols:        st.game.Cols,
+		Connect:     st.game.Connect,
+		Rated:       st.rated,
+		Turn:        st.turn,
+		Board:       st.game.Board(),
+		Moves:       st.moves,
+		TimeControl: st.clock.tc.String(),
+		StartedAt:   st.startAt,
+		Hints:       st.hints,
+	}
+	if st.p1.bot != nil || st.p2.bot != nil {
+		g.Engine = st.opts.engine
+	}
+	if st.clock.tc.Enabled() {
+		g.ClockMs = map[string]int64{
+			"R": st.clock.remaining("R", st.turn).Milliseconds(),
+			"Y": st.clock.remaining("Y", st.turn).Milliseconds(),
+		}
+	}
+	if err := m.Store.SaveActiveGame(context.Background(), g); err != nil {
+		log.Printf("game %s: checkpoint failed: %v", st.gameID, err)
+	}
+}
+
+// Restore reloads the games that were in progress when the server stopped
+// and reports how many it got back. Call it before serving.
+func (m *Manager) Restore(ctx context.Context) (int, error) {
+	saved, err := m.Store.ActiveGames(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, g := range saved {
+		if err := m.restore(g); err != nil {
+			log.Printf("game %s: can't restore, dropping it: %v", g.GameID, err)
+			_ = m.Store.DeleteActiveGame(ctx, g.GameID)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *Manager) restore(g models.ActiveGame) error {
+	variant := Variant{Rows: g.Rows, Cols: g.Cols, Connect: g.Connect}
+	board, err := NewVariantGame(variant)
+	if err != nil {
+		return err
+	}
+	for i, mv := range g.Moves {
+		if _, ok := board.DropDisc(mv.Col, mv.Player); !ok {
+			return fmt.Errorf("move %d: column %d is full", i+1, mv.Col)
+		}
+	}
+	tc, err := ParseTimeControl(g.TimeControl)
+	if err != nil {
+		return err
+	}
+	opts := joinOpts{variant: variant, engine: g.Engine, rated: g.Rated, tc: tc}
+	p1 := playerConn{username: g.Player1, side: "R"}
+	p2 := playerConn{username: g.Player2, side: "Y"}
+	if g.Engine != "" {
+		// a fresh engine; they only ever see the position they're given
+		bot, err := NewEngine(g.Engine)
+		if err != nil {
+			return err
+		}
+		if p1.username == store.BotName {
+			p1.bot = bot
+		} else {
+			p2.bot = bot
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.newState(g.GameID, p1, p2, board, opts)
+	st.turn = g.Turn
+	st.startAt = g.StartedAt
+	st.moves = g.Moves
+	for side, n := range g.Hints {
+		st.hints[side] = n
+	}
+	if tc.Enabled() {
+		st.clock.left = map[string]time.Duration{
+			"R": time.Duration(g.ClockMs["R"]) * time.Millisecond,
+			"Y": time.Duration(g.ClockMs["Y"]) * time.Millisecond,
+		}
+		// nobody's time goes while the server was down or they're on
+		// their way back; a bot to move waits with it
+		st.clock.paused = true
+	} else if st.seat(st.turn).bot != nil {
+		m.botMove(st)
+	}
+	// nobody is connected yet: everyone gets the usual grace to come back,
+	// and if neither does the game is called off
+	for _, pc := range []*playerConn{&st.p1, &st.p2} {
+		if pc.bot == nil {
+			m.onDisconnect(st, pc.side, nil)
+		}
+	}
+	go m.run(st)
+	return nil
+}
+
+// unpause starts a restored game's clock, and its bot if it's to move, when
+// the first player is back; runs on st's goroutine.
+func (m *Manager) unpause(st *state) {
+	if !st.clock.paused {
+		return
+	}
+	st.clock.paused = false
+	m.runClock(st, st.turn)
+	if st.seat(st.turn).bot != nil {
+		m.botMove(st)
+	}
+}
+
+// Shutdown stops taking new games and parks the ones in progress: each is
+// checkpointed, its players are told to come back after the restart and
+// its goroutine stops without finishing the game. People still waiting for
+// an opponent are sent away.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	// everyone we hang up on, so we can wait for the goodbyes to go out
+	var mu sync.Mutex
+	var closed []*client
+
+	m.mu.Lock()
+	m.closing = true
+	for key, entries := range m.queue {
+		for _, e := range entries {
+			e.botTimer.Stop()
+			e.closed = true
+			e.conn.send(errShuttingDown)
+			e.conn.close()
+			closed = append(closed, e.conn)
+		}
+		delete(m.queue, key)
+	}
+	for code, rm := range m.rooms {
+		rm.timer.Stop()
+		rm.closed = true
+		rm.conn.send(errShuttingDown)
+		rm.conn.close()
+		closed = append(closed, rm.conn)
+		delete(m.rooms, code)
+	}
+	games := make([]*state, 0, len(m.active))
+	for _, st := range m.active {
+		games = append(games, st)
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, st := range games {
+		wg.Add(1)
+		go func(st *state) {
+			defer wg.Done()
+			var conns []*client
+			st.call(func() { conns = m.park(st) })
+			mu.Lock()
+			closed = append(closed, conns...)
+			mu.Unlock()
+		}(st)
+	}
+	parked := make(chan struct{})
+	go func() {
+		wg.Wait()
+		for _, c := range closed {
+			<-c.done
+		}
+		close(parked)
+	}()
+	select {
+	case <-parked:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// park checkpoints st and stops it for Shutdown, returning the sockets it
+// closed; runs on st's goroutine.
+func (m *Manager) park(st *state) []*client {
+	if st.over {
+		return nil // already stored as finished
+	}
+	st.parked = true
+	st.cancel()
+	st.clock.stop()
+	for _, t := range []*time.Timer{st.rejoinP1, st.rejoinP2} {
+		if t != nil {
+			t.Stop()
+		}
+	}
+	for _, b := range []Engine{st.p1.bot, st.p2.bot} {
+		if c, ok := b.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+	m.checkpoint(st)
+	m.broadcast(st, protocol.Info{Message: "The server is restarting; reconnect in a moment to carry on with this game"})
+	var closed []*client
+	for conn := range st.spectators {
+		closed = append(closed, conn)
+	}
+	for _, conn := range []*client{st.p1.conn, st.p2.conn} {
+		if conn != nil {
+			closed = append(closed, conn)
+		}
+	}
+	for _, conn := range closed {
+		conn.close()
+	}
+	return closed
+}
+
+var errShuttingDown = protocol.Error{Code: protocol.CodeShuttingDown, Message: "the server is restarting, try again in a moment"}
+
+// refuse turns conn away if the server is shutting down; caller holds m.mu.
+func (m *Manager) refuse(conn *client) bool {
+	if !m.closing {
+		return false
+	}
+	conn.send(errShuttingDown)
+	conn.close()
+	return true
+}
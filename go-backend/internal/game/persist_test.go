@@ -0,0 +1,180 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// restart shuts m down and brings up a new Manager on the same store with
+// the games m had in progress and its secrets, so resume tokens and logins
+// still work.
+func restart(t *testing.T, m *Manager) *Manager {
+	t.Helper()
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	m2 := NewManager(m.Store, int(m.MatchBotAfter/time.Millisecond), int(m.RejoinGrace/time.Millisecond), 0, int(time.Minute/time.Millisecond), 0)
+	m2.TokenSecret, m2.Sessions = m.TokenSecret, m.Sessions
+	if _, err := m2.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return m2
+}
+
+func TestShutdownAndRestore(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+	alice, bob, gameID := pairUp(t, dial, "&time=60%2B0")
+	play(t, alice, bob, 0, 1, 0)
+	carol := dial(as("carol"))
+	expect(t, carol, "queued")
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, alice, "info")
+	if msg := expect(t, carol, "error"); msg["code"] != "shutting_down" {
+		t.Errorf("to the queue: %v", msg)
+	}
+	if _, _, err := alice.ReadMessage(); err == nil {
+		t.Error("alice still connected after Shutdown")
+	}
+	rec := httptest.NewRecorder()
+	m.HandleWS(rec, httptest.NewRequest(http.MethodGet, "/ws?"+as("dave"), nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("new connection while shutting down: HTTP %d", rec.Code)
+	}
+
+	saved, err := m.Store.ActiveGames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].GameID != gameID || len(saved[0].Moves) != 3 || saved[0].Turn != "Y" || saved[0].ClockMs["Y"] == 0 {
+		t.Fatalf("checkpoint: %+v", saved)
+	}
+
+	m = restart(t, m)
+	dial = serve(t, m)
+	bob = dial("resume=" + m.resumeToken(gameID, "Y"))
+	if msg := expect(t, bob, "rejoined"); msg["turn"] != "Y" || msg["color"] != "Y" {
+		t.Fatalf("rejoined: %v", msg)
+	}
+	alice = dial("resume=" + m.resumeToken(gameID, "R"))
+	expect(t, alice, "rejoined")
+	play(t, bob, alice, 1, 0, 1, 0)
+	if msg := expect(t, bob, "gameOver"); msg["result"] != "alice wins" {
+		t.Errorf("gameOver: %v", msg)
+	}
+	waitFor(t, "the checkpoint to go", func() bool {
+		saved, _ := m.Store.ActiveGames(context.Background())
+		return len(saved) == 0
+	})
+}
+
+func TestRestoreBotGame(t *testing.T) {
+	m := testManager(time.Minute)
+	m.MatchBotAfter = 10 * time.Millisecond
+	alice := serve(t, m)(as("alice") + "&difficulty=easy")
+	gameID := expect(t, alice, "start")["gameId"].(string)
+	send(t, alice, "move", "col", 3)
+	expect(t, alice, "update")
+	expect(t, alice, "update") // the bot's reply
+
+	m = restart(t, m)
+	alice = serve(t, m)("resume=" + m.resumeToken(gameID, "R"))
+	if msg := expect(t, alice, "rejoined"); msg["turn"] != "R" {
+		t.Fatalf("rejoined: %v", msg)
+	}
+	send(t, alice, "move", "col", 3)
+	expect(t, alice, "update")
+	expect(t, alice, "update") // the restored bot still answers
+}
+
+// A restored game neither player comes back to is called off, not won.
+func TestRestoredGameNobodyRejoins(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, gameID := pairUp(t, serve(t, m), "")
+	play(t, alice, bob, 3)
+
+	m.RejoinGrace = 50 * time.Millisecond
+	m = restart(t, m)
+	waitFor(t, "the game to be aborted", func() bool {
+		saved, _ := m.Store.ActiveGames(context.Background())
+		return len(saved) == 0
+	})
+	if _, err := m.Store.GetGame(context.Background(), gameID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("aborted game stored: %v", err)
+	}
+	if p := storedPlayer(t, m, "alice"); p.Wins+p.Losses+p.Draws != 0 {
+		t.Errorf("aborted game counted: %+v", p)
+	}
+}
+
+// Nobody's clock runs between the shutdown and the first rejoin.
+func TestRestoredClocksWaitForARejoin(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, gameID := pairUp(t, serve(t, m), "&time=60%2B0")
+	play(t, alice, bob, 3)
+
+	m = restart(t, m)
+	saved, err := m.Store.ActiveGames(context.Background())
+	if err != nil || len(saved) != 1 {
+		t.Fatalf("checkpoint: %+v, %v", saved, err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	bob = serve(t, m)("resume=" + m.resumeToken(gameID, "Y"))
+	clock := expect(t, bob, "rejoined")["clock"].(map[string]any)
+	if left := int64(clock["Y"].(float64)); left < saved[0].ClockMs["Y"]-100 {
+		t.Errorf("Y has %dms on rejoining, %dms at the checkpoint", left, saved[0].ClockMs["Y"])
+	}
+}
+
+// A restored bot that's to move waits for its opponent to come back too.
+func TestRestoredBotWaitsForARejoin(t *testing.T) {
+	m := testManager(time.Minute)
+	m.MatchBotAfter = 10 * time.Millisecond
+	m.BotDelay = time.Hour // still thinking when the server stops
+	alice := serve(t, m)(as("alice") + "&difficulty=easy&time=60%2B0")
+	gameID := expect(t, alice, "start")["gameId"].(string)
+	send(t, alice, "move", "col", 3)
+	expect(t, alice, "update")
+
+	m = restart(t, m)
+	time.Sleep(100 * time.Millisecond)
+	if saved, _ := m.Store.ActiveGames(context.Background()); len(saved) != 1 || saved[0].Turn != "Y" {
+		t.Fatalf("the bot moved with nobody there: %+v", saved)
+	}
+	alice = serve(t, m)("resume=" + m.resumeToken(gameID, "R"))
+	expect(t, alice, "rejoined")
+	if msg := expect(t, alice, "update"); msg["turn"] != "R" {
+		t.Errorf("bot's move: %v", msg)
+	}
+}
+
+// Games are kept without a fixed TokenSecret too, but the tokens from
+// before the restart don't get anyone back in.
+func TestRestoreWithANewSecret(t *testing.T) {
+	m := testManager(time.Minute)
+	alice, bob, gameID := pairUp(t, serve(t, m), "")
+	play(t, alice, bob, 3)
+	token := m.resumeToken(gameID, "R")
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	m2 := NewManager(m.Store, int(time.Hour/time.Millisecond), int(time.Hour/time.Millisecond), 0, int(time.Minute/time.Millisecond), 0)
+	m2.Sessions = m.Sessions
+	if n, err := m2.Restore(context.Background()); n != 1 || err != nil {
+		t.Fatalf("Restore: %d, %v", n, err)
+	}
+	alice = serve(t, m2)("resume=" + token)
+	if msg := expect(t, alice, "error"); msg["code"] != "forbidden" {
+		t.Errorf("old token: %v", msg)
+	}
+}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position encodes the board as its rows from the top separated by '/',
+// one character per cell: '.' empty, 'R' or 'Y'. A standard board after
+// one move in the centre is "......./......./......./......./......./...R...".
+func (g *GameLogic) Position() string {
+	var sb strings.Builder
+	for r := 0; r < g.Rows; r++ {
+		if r > 0 {
+			sb.WriteByte('/')
+		}
+		for c := 0; c < g.Cols; c++ {
+			switch g.Cell(r, c) {
+			case "R":
+				sb.WriteByte('R')
+			case "Y":
+				sb.WriteByte('Y')
+			default:
+				sb.WriteByte('.')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// ParsePosition is the inverse of Position; connect isn't part of the
+// encoding so it has to be given.
+func ParsePosition(pos string, connect int) (*GameLogic, error) {
+	rows := strings.Split(pos, "/")
+	cells := make([][]string, len(rows))
+	for r, row := range rows {
+		cells[r] = make([]string, len(row))
+		for c, ch := range row {
+			switch ch {
+			case 'R', 'r':
+				cells[r][c] = "R"
+			case 'Y', 'y':
+				cells[r][c] = "Y"
+			case '.':
+			default:
+				return nil, fmt.Errorf("bad cell %q in position", ch)
+			}
+		}
+	}
+	return fromCells(cells, connect)
+}
+
+// FromBoard rebuilds a GameLogic from the wire board shape.
+func FromBoard(board [][]*string, connect int) (*GameLogic, error) {
+	cells := make([][]string, len(board))
+	for r := range board {
+		cells[r] = make([]string, len(board[r]))
+		for c, v := range board[r] {
+			if v != nil {
+				cells[r][c] = *v
+			}
+		}
+	}
+	return fromCells(cells, connect)
+}
+
+func fromCells(cells [][]string, connect int) (*GameLogic, error) {
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("empty board")
+	}
+	g, err := NewVariantGame(Variant{Rows: len(cells), Cols: len(cells[0]), Connect: connect})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range cells {
+		if len(row) != g.Cols {
+			return nil, fmt.Errorf("ragged board")
+		}
+	}
+	for c := 0; c < g.Cols; c++ {
+		gap := false
+		for r := g.Rows - 1; r >= 0; r-- {
+			v := cells[r][c]
+			if v == "" {
+				gap = true
+				continue
+			}
+			side := sideIndex(v)
+			if side < 0 {
+				return nil, fmt.Errorf("bad cell %q", v)
+			}
+			if gap {
+				return nil, fmt.Errorf("floating disc in column %d", c)
+			}
+			g.play(c, side)
+		}
+	}
+	return g, nil
+}
+
+// ToMove is whose turn it is, assuming "R" moved first.
+func (g *GameLogic) ToMove() string {
+	if g.moves%2 == 0 {
+		return "R"
+	}
+	return "Y"
+}
@@ -0,0 +1,46 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPositionRoundTrip(t *testing.T) {
+	g := playMoves(t, Standard, []int{3, 3, 2, 4, 0})
+	pos := g.Position()
+	if want := "......./......./......./......./...Y.../R.RRY.."; pos != want {
+		t.Fatalf("Position() = %q, want %q", pos, want)
+	}
+	back, err := ParsePosition(pos, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Position() != pos || back.MoveCount() != 5 || back.ToMove() != "Y" {
+		t.Errorf("parsed back as %q, %d moves, %s to move", back.Position(), back.MoveCount(), back.ToMove())
+	}
+	fb, err := FromBoard(g.Board(), 4)
+	if err != nil || fb.Position() != pos {
+		t.Errorf("FromBoard: %v, %v", fb, err)
+	}
+}
+
+func TestParsePositionErrors(t *testing.T) {
+	for pos, want := range map[string]string{
+		"":        "board must be",
+		"..R/...": "floating disc",
+		"..../..": "ragged",
+		"x..":     "bad cell",
+		".../.R.": "",
+	} {
+		_, err := ParsePosition(pos, 2)
+		if want == "" {
+			if err != nil {
+				t.Errorf("ParsePosition(%q): %v", pos, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParsePosition(%q): %v, want an error containing %q", pos, err, want)
+		}
+	}
+}
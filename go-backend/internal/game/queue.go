@@ -0,0 +1,231 @@
+package game
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// lobbyConn is a socket waiting for a game, either in the queue or hosting a
+// private room. Its reader keeps running once the game starts and then feeds
+// that game, which is how we notice sockets that close while waiting.
+type lobbyConn struct {
+	username string
+	conn     *client
+	st       *state // set under m.mu when the game starts
+	side     string
+	closed   bool   // socket went away before a game started
+	leave    func() // called under m.mu to drop out of the queue / room
+}
+
+func (m *Manager) lobbyLoop(lc *lobbyConn) {
+	for {
+		msg, err := lc.conn.read()
+		m.mu.Lock()
+		st, side := lc.st, lc.side
+		if st != nil {
+			st, side = st.latest(lc.username)
+		}
+		if err != nil && st == nil {
+			lc.closed = true
+			lc.leave()
+		}
+		m.mu.Unlock()
+		if err != nil {
+			if st != nil {
+				st.post(func() { m.onDisconnect(st, side, lc.conn) })
+			}
+			return
+		}
+		if st != nil {
+			st.post(func() { m.handleMessage(st, side, msg) })
+		} else {
+			m.lobbyMessage(lc, msg)
+		}
+	}
+}
+
+// lobbyMessage answers a message sent before the game started.
+func (m *Manager) lobbyMessage(lc *lobbyConn, msg []byte) {
+	reply := protocol.Error{Code: protocol.CodeNotInGame, Message: "waiting for an opponent, no game yet", Seq: protocol.PeekSeq(msg)}
+	if _, err := protocol.DecodeClient(msg); err != nil {
+		reply = protocol.AsError(err)
+	}
+	lc.conn.send(reply)
+}
+
+// queueKey groups players that can be paired with each other.
+type queueKey struct {
+	variant Variant
+	rated   bool
+	tc      TimeControl
+}
+
+type queueEntry struct {
+	lobbyConn
+	opts     joinOpts
+	rating   int
+	joinedAt time.Time
+	botTimer *time.Timer
+}
+
+// ratingWindow is how far apart two ratings may be for e to accept a match;
+// it widens the longer e waits. Zero MatchRatingWindow means plain FIFO.
+func (m *Manager) ratingWindow(e *queueEntry) int {
+	if m.MatchRatingWindow <= 0 {
+		return -1
+	}
+	return m.MatchRatingWindow * (1 + int(time.Since(e.joinedAt)/(10*time.Second)))
+}
+
+func (m *Manager) compatible(a, b *queueEntry) bool {
+	if a.username == b.username {
+		return false
+	}
+	wa, wb := m.ratingWindow(a), m.ratingWindow(b)
+	if wa < 0 || wb < 0 {
+		return true
+	}
+	diff := a.rating - b.rating
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= wa && diff <= wb
+}
+
+func (m *Manager) enqueue(conn *client, username string, opts joinOpts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.refuse(conn) || m.busy(conn, username) {
+		return
+	}
+	for _, entries := range m.queue {
+		for _, e := range entries {
+			if e.username == username {
+				conn.send(protocol.Error{Code: protocol.CodeAlreadyQueued, Message: "you are already waiting in the queue"})
+				conn.close()
+				return
+			}
+		}
+	}
+
+	key := queueKey{variant: opts.variant, rated: opts.rated, tc: opts.tc}
+	e := &queueEntry{
+		lobbyConn: lobbyConn{username: username, conn: conn},
+		opts:      opts,
+		rating:    opts.rating,
+		joinedAt:  time.Now(),
+	}
+	e.leave = func() { m.dequeue(key, e) }
+	e.botTimer = time.AfterFunc(m.MatchBotAfter, func() { m.startBotGame(key, e) })
+	m.queue[key] = append(m.queue[key], e)
+	go m.lobbyLoop(&e.lobbyConn)
+	m.pairQueue(key)
+	m.broadcastQueue(key)
+}
+
+// dequeue removes e from the queue; caller holds m.mu.
+func (m *Manager) dequeue(key queueKey, e *queueEntry) {
+	entries := m.queue[key]
+	for i, w := range entries {
+		if w == e {
+			e.botTimer.Stop()
+			m.queue[key] = append(entries[:i:i], entries[i+1:]...)
+			if len(m.queue[key]) == 0 {
+				delete(m.queue, key)
+			}
+			m.broadcastQueue(key)
+			return
+		}
+	}
+}
+
+// pairQueue starts a game for every compatible pair, oldest entries first,
+// and reports whether it paired anyone. Caller holds m.mu.
+func (m *Manager) pairQueue(key queueKey) (matched bool) {
+	for paired := true; paired; {
+		paired = false
+		entries := m.queue[key]
+	scan:
+		for i := 0; i < len(entries); i++ {
+			for j := i + 1; j < len(entries); j++ {
+				a, b := entries[i], entries[j]
+				if !m.compatible(a, b) {
+					continue
+				}
+				a.botTimer.Stop()
+				b.botTimer.Stop()
+				rest := make([]*queueEntry, 0, len(entries)-2)
+				for _, w := range entries {
+					if w != a && w != b {
+						rest = append(rest, w)
+					}
+				}
+				m.queue[key] = rest
+				st := m.startGame(playerConn{username: a.username, conn: a.conn, side: "R", reading: true},
+					playerConn{username: b.username, conn: b.conn, side: "Y", reading: true}, a.opts)
+				a.st, a.side = st, "R"
+				b.st, b.side = st, "Y"
+				paired, matched = true, true
+				break scan
+			}
+		}
+	}
+	if len(m.queue[key]) == 0 {
+		delete(m.queue, key)
+	}
+	return matched
+}
+
+func (m *Manager) broadcastQueue(key queueKey) {
+	entries := m.queue[key]
+	for i, e := range entries {
+		e.conn.send(protocol.Queued{Message: "Waiting for opponent...", Position: i + 1, Waiting: len(entries)})
+	}
+}
+
+// pairLoop re-runs pairing so rating windows that widen over time can match
+// players even when nobody new arrives.
+func (m *Manager) pairLoop() {
+	for range time.Tick(2 * time.Second) {
+		m.mu.Lock()
+		for key := range m.queue {
+			if m.pairQueue(key) {
+				m.broadcastQueue(key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *Manager) startBotGame(key queueKey, e *queueEntry) {
+	m.mu.Lock()
+	if e.st != nil || e.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.dequeue(key, e)
+	m.mu.Unlock()
+
+	// external engines start a process here, so keep it outside the lock
+	eng, err := NewEngine(e.opts.engine)
+	if err != nil {
+		log.Printf("engine %s: %v, using %s", e.opts.engine, err, Easy)
+		eng = Bot{Level: Easy}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e.closed {
+		if c, ok := eng.(io.Closer); ok {
+			_ = c.Close()
+		}
+		return
+	}
+	p1 := playerConn{username: e.username, conn: e.conn, side: "R", reading: true}
+	p2 := playerConn{username: "BOT", conn: nil, side: "Y", bot: eng}
+	e.st = m.startGame(p1, p2, e.opts)
+	e.side = "R"
+}
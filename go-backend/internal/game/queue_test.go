@@ -0,0 +1,134 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// join queues name with opts and returns their socket.
+func join(t *testing.T, m *Manager, name string, opts joinOpts) *websocket.Conn {
+	t.Helper()
+	s, c := wsPair(t)
+	if opts.variant == (Variant{}) {
+		opts.variant = Standard
+	}
+	if opts.engine == "" {
+		opts.engine = "easy"
+	}
+	m.enqueue(s, name, opts)
+	return c
+}
+
+func TestQueuePairsInOrder(t *testing.T) {
+	m := testManager(time.Minute)
+	alice := join(t, m, "alice", joinOpts{})
+	expect(t, alice, "queued")
+	bob := join(t, m, "bob", joinOpts{})
+	carol := join(t, m, "carol", joinOpts{})
+	if msg := expect(t, alice, "start"); msg["color"] != "R" || msg["opponent"] != "bob" {
+		t.Errorf("alice: %v", msg)
+	}
+	if msg := expect(t, bob, "start"); msg["color"] != "Y" || msg["opponent"] != "alice" {
+		t.Errorf("bob: %v", msg)
+	}
+	if msg := expect(t, carol, "queued"); msg["position"] != float64(1) || msg["waiting"] != float64(1) {
+		t.Errorf("carol: %v", msg)
+	}
+}
+
+func TestQueueKeepsSettingsApart(t *testing.T) {
+	m := testManager(time.Minute)
+	join(t, m, "alice", joinOpts{rated: true})
+	join(t, m, "bob", joinOpts{rated: false})
+	join(t, m, "carol", joinOpts{rated: true, variant: Variant{Rows: 7, Cols: 8, Connect: 4}})
+	time.Sleep(50 * time.Millisecond)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.active) != 0 || len(m.queue) != 3 {
+		t.Errorf("%d games and %d queues, want 0 and 3", len(m.active), len(m.queue))
+	}
+}
+
+func TestQueueRatingWindow(t *testing.T) {
+	m := testManager(time.Minute)
+	m.MatchRatingWindow = 100
+	alice := join(t, m, "alice", joinOpts{rating: 1500})
+	bob := join(t, m, "bob", joinOpts{rating: 1800})
+	expect(t, bob, "queued")
+	carol := join(t, m, "carol", joinOpts{rating: 1580})
+	if msg := expect(t, alice, "start"); msg["opponent"] != "carol" {
+		t.Errorf("alice: %v", msg)
+	}
+	expect(t, carol, "start")
+	if msg := expect(t, bob, "queued"); msg["waiting"] != float64(1) {
+		t.Errorf("bob: %v", msg)
+	}
+}
+
+func TestRatingWindowWidens(t *testing.T) {
+	m := testManager(time.Minute)
+	m.MatchRatingWindow = 100
+	now := time.Now()
+	a := &queueEntry{lobbyConn: lobbyConn{username: "a"}, rating: 1500, joinedAt: now.Add(-25 * time.Second)}
+	if got := m.ratingWindow(a); got != 300 {
+		t.Errorf("window after 25s = %d, want 300", got)
+	}
+	for _, tt := range []struct {
+		waited time.Duration
+		want   bool
+	}{{0, false}, {15 * time.Second, false}, {30 * time.Second, true}} {
+		// 250 apart: a takes it after 25s, b needs 20s or more
+		b := &queueEntry{lobbyConn: lobbyConn{username: "b"}, rating: 1750, joinedAt: now.Add(-tt.waited)}
+		if got := m.compatible(a, b); got != tt.want {
+			t.Errorf("b waited %v: compatible = %v, want %v", tt.waited, got, tt.want)
+		}
+	}
+	if m.compatible(a, &queueEntry{lobbyConn: lobbyConn{username: "a"}, rating: 1500, joinedAt: now}) {
+		t.Error("paired a player with themselves")
+	}
+	m.MatchRatingWindow = 0
+	if !m.compatible(a, &queueEntry{lobbyConn: lobbyConn{username: "c"}, rating: 2400, joinedAt: now}) {
+		t.Error("no window should pair anyone")
+	}
+}
+
+func TestQueueLeavesWhenSocketCloses(t *testing.T) {
+	m := testManager(time.Minute)
+	alice := join(t, m, "alice", joinOpts{})
+	expect(t, alice, "queued")
+	_ = alice.Close()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		m.mu.Lock()
+		n := len(m.queue)
+		m.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("closed socket still queued")
+		}
+	}
+	bob := join(t, m, "bob", joinOpts{})
+	expect(t, bob, "queued")
+}
+
+func TestQueueRefusesTwice(t *testing.T) {
+	m := testManager(time.Minute)
+	join(t, m, "alice", joinOpts{})
+	again := join(t, m, "alice", joinOpts{})
+	if msg := expect(t, again, "error"); !strings.Contains(msg["message"].(string), "already waiting") {
+		t.Errorf("second join: %v", msg)
+	}
+}
+
+func TestQueueFallsBackToBot(t *testing.T) {
+	m := testManager(time.Minute)
+	m.MatchBotAfter = 50 * time.Millisecond
+	alice := join(t, m, "alice", joinOpts{engine: "medium"})
+	if msg := expect(t, alice, "start"); msg["opponent"] != "BOT" || msg["color"] != "R" {
+		t.Errorf("start: %v", msg)
+	}
+}
@@ -0,0 +1,210 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// A replay streams a finished game from the store back over /ws?replay=,
+// move by move as Update messages, waiting between moves as long as the
+// players did (divided by the speed). The viewer can pause, play, step and
+// seek; every command is answered with a ReplayState. Nothing is shared
+// with live games, so a replay is just its own goroutine and the socket.
+
+const (
+	replayMinSpeed   = 1.0 / 64
+	replayMaxSpeed   = 64
+	replayDefaultGap = time.Second      // between moves of games stored without times
+	replayMaxGap     = 10 * time.Second // long thinks are cut short, before speed
+	replayLoadWait   = 5 * time.Second
+)
+
+// ParseSpeed reads a ?speed= multiplier; empty means 1.
+func ParseSpeed(s string) (float64, error) {
+	if s == "" {
+		return 1, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || !validSpeed(v) {
+		return 0, fmt.Errorf("invalid speed %q (%g to %d)", s, replayMinSpeed, replayMaxSpeed)
+	}
+	return v, nil
+}
+
+func validSpeed(v float64) bool {
+	return v >= replayMinSpeed && v <= replayMaxSpeed
+}
+
+type replayer struct {
+	conn    *client
+	rec     Record
+	result  protocol.GameOver
+	game    *GameLogic
+	ply     int
+	speed   float64
+	playing bool
+}
+
+// replay loads gameID and plays it to conn until the viewer hangs up.
+func (m *Manager) replay(conn *client, gameID string, speed float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), replayLoadWait)
+	doc, err := m.Store.GetGame(ctx, gameID)
+	cancel()
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("replay %s: %v", gameID, err)
+		}
+		conn.send(protocol.Error{Code: protocol.CodeNotFound, Message: "game not found"})
+		conn.close()
+		return
+	}
+	rp := &replayer{conn: conn, rec: RecordFromDoc(doc), speed: speed, playing: true}
+	rp.result = protocol.GameOver{Result: doc.Winner + " wins", Reason: doc.Reason}
+	if doc.Winner == "Draw" {
+		rp.result.Result = "Draw"
+	}
+	if rp.game, err = NewVariantGame(rp.rec.Variant); err != nil {
+		conn.send(protocol.Error{Code: protocol.CodeNotFound, Message: "stored game can't be replayed: " + err.Error()})
+		conn.close()
+		return
+	}
+	conn.send(protocol.Replay{
+		GameID:  rp.rec.GameID,
+		Players: protocol.Players{R: rp.rec.Red, Y: rp.rec.Yellow},
+		Rows:    rp.game.Rows,
+		Cols:    rp.game.Cols,
+		Connect: rp.game.Connect,
+		Moves:   len(rp.rec.Moves),
+		Speed:   rp.speed,
+		Playing: rp.playing,
+	})
+	if len(rp.rec.Moves) == 0 {
+		conn.send(rp.result) // over before anyone moved
+	}
+
+	cmds := make(chan protocol.ClientMessage)
+	go rp.readLoop(cmds)
+	go rp.run(cmds)
+}
+
+// readLoop hands the viewer's commands to run and turns away anything else.
+func (rp *replayer) readLoop(cmds chan<- protocol.ClientMessage) {
+	defer close(cmds)
+	for {
+		msg, err := rp.conn.read()
+		if err != nil {
+			return
+		}
+		in, err := protocol.DecodeClient(msg)
+		switch in.(type) {
+		case *protocol.Pause, *protocol.Play, *protocol.Step, *protocol.Seek:
+			cmds <- in
+		case nil:
+			rp.conn.send(protocol.AsError(err))
+		default:
+			rp.conn.send(protocol.Error{Code: protocol.CodeReadOnly, Message: "a replay only takes pause, play, step and seek", Seq: in.Sequence()})
+		}
+	}
+}
+
+// run owns the replay: it plays the next move whenever the wait is up and
+// acts on commands in between.
+func (rp *replayer) run(cmds <-chan protocol.ClientMessage) {
+	defer rp.conn.close()
+	for {
+		var next <-chan time.Time
+		if rp.playing && rp.ply < len(rp.rec.Moves) {
+			next = time.After(rp.gap())
+		}
+		select {
+		case in, ok := <-cmds:
+			if !ok {
+				return
+			}
+			if rej := rp.command(in); rej != nil {
+				rej.Seq = in.Sequence()
+				rp.conn.send(*rej)
+			}
+		case <-next:
+			rp.forward()
+		}
+	}
+}
+
+func (rp *replayer) command(in protocol.ClientMessage) *protocol.Error {
+	switch in := in.(type) {
+	case *protocol.Pause:
+		rp.playing = false
+	case *protocol.Play:
+		if in.Speed != 0 {
+			if !validSpeed(in.Speed) {
+				return reject(protocol.CodeInvalidSpeed, fmt.Sprintf("speed must be %g to %d", replayMinSpeed, replayMaxSpeed))
+			}
+			rp.speed = in.Speed
+		}
+		rp.playing = true
+	case *protocol.Step:
+		if rp.ply == len(rp.rec.Moves) {
+			return reject(protocol.CodeReplayEnd, "no more moves")
+		}
+		rp.playing = false
+		rp.forward()
+	case *protocol.Seek:
+		if in.Ply < 0 || in.Ply > len(rp.rec.Moves) {
+			return reject(protocol.CodeInvalidPly, fmt.Sprintf("ply %d is outside the game (0-%d)", in.Ply, len(rp.rec.Moves)))
+		}
+		rp.seek(in.Ply)
+	}
+	rp.conn.send(protocol.ReplayState{
+		Ply:     rp.ply,
+		Board:   rp.game.Board(),
+		Turn:    sideToMove(rp.ply),
+		Speed:   rp.speed,
+		Playing: rp.playing,
+	})
+	if _, ok := in.(*protocol.Seek); ok && rp.ply == len(rp.rec.Moves) {
+		rp.conn.send(rp.result)
+	}
+	return nil
+}
+
+// gap is how long to wait before the next move.
+func (rp *replayer) gap() time.Duration {
+	d := replayDefaultGap
+	if rp.rec.Times != nil {
+		d = min(rp.rec.Times[rp.ply], replayMaxGap)
+	}
+	return time.Duration(float64(d) / rp.speed)
+}
+
+// forward plays the next move, and the result after the last one.
+func (rp *replayer) forward() {
+	side := sideToMove(rp.ply)
+	col := rp.rec.Moves[rp.ply]
+	row, _ := rp.game.DropDisc(col, side) // stored games only hold legal moves
+	rp.ply++
+	rp.conn.send(protocol.Update{
+		Move:  protocol.Played{Row: row, Col: col, Player: side},
+		Board: rp.game.Board(),
+		Turn:  sideToMove(rp.ply),
+	})
+	if rp.ply == len(rp.rec.Moves) {
+		rp.conn.send(rp.result)
+	}
+}
+
+// seek rebuilds the position after ply moves.
+func (rp *replayer) seek(ply int) {
+	rp.game, _ = NewVariantGame(rp.rec.Variant)
+	for i, col := range rp.rec.Moves[:ply] {
+		rp.game.DropDisc(col, sideToMove(i))
+	}
+	rp.ply = ply
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+// storeReplay stores a game alice won in column 0, a fifth of a second a
+// move, and returns its id.
+func storeReplay(t *testing.T, m *Manager) string {
+	t.Helper()
+	start := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	var moves []models.Move
+	for i, col := range []int{0, 1, 0, 1, 0, 1, 0} {
+		moves = append(moves, models.Move{Player: sideToMove(i), Col: col, At: start.Add(time.Duration(i+1) * 200 * time.Millisecond)})
+	}
+	g := models.GameDoc{
+		GameID: "g1", Player1: "alice", Player2: "bob", Winner: "alice", Reason: "connect",
+		Moves: moves, Rows: 6, Cols: 7, Connect: 4, Duration: 2, CreatedAt: start.Add(2 * time.Second),
+	}
+	if err := m.Store.InsertGame(context.Background(), g); err != nil {
+		t.Fatal(err)
+	}
+	return g.GameID
+}
+
+func TestReplay(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+	gameID := storeReplay(t, m)
+
+	viewer := dial("replay=" + gameID + "&speed=4")
+	if msg := expect(t, viewer, "replay"); msg["moves"] != float64(7) || msg["playing"] != true {
+		t.Fatalf("replay: %v", msg)
+	}
+	expect(t, viewer, "update")
+	send(t, viewer, "pause", "seq", 1)
+	paused := expect(t, viewer, "replayState")
+	if paused["playing"] != false {
+		t.Fatalf("after pause: %v", paused)
+	}
+	ply := paused["ply"].(float64)
+
+	send(t, viewer, "step")
+	if msg := expect(t, viewer, "update"); msg["move"].(map[string]any)["col"] != float64(int(ply)%2) {
+		t.Errorf("step played %v", msg["move"])
+	}
+	if msg := expect(t, viewer, "replayState"); msg["ply"] != ply+1 || msg["playing"] != false {
+		t.Errorf("after step: %v", msg)
+	}
+
+	send(t, viewer, "seek", "ply", 6)
+	if msg := expect(t, viewer, "replayState"); msg["ply"] != float64(6) || msg["turn"] != "R" {
+		t.Errorf("after seek: %v", msg)
+	}
+	send(t, viewer, "play", "speed", 64)
+	expect(t, viewer, "replayState")
+	expect(t, viewer, "update")
+	if msg := expect(t, viewer, "gameOver"); msg["result"] != "alice wins" || msg["reason"] != "connect" {
+		t.Errorf("gameOver: %v", msg)
+	}
+
+	// seeking to the end shows the result again, and back to the start
+	// clears the board
+	send(t, viewer, "seek", "ply", 7)
+	expect(t, viewer, "replayState")
+	expect(t, viewer, "gameOver")
+	send(t, viewer, "seek", "ply", 0)
+	if msg := expect(t, viewer, "replayState"); msg["turn"] != "R" || msg["board"].([]any)[5].([]any)[0] != nil {
+		t.Errorf("back at the start: %v", msg)
+	}
+}
+
+func TestReplayRejects(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+	gameID := storeReplay(t, m)
+
+	if msg := expect(t, dial("replay=nope"), "error"); msg["code"] != "not_found" {
+		t.Errorf("unknown game: %v", msg)
+	}
+	viewer := dial("replay=" + gameID)
+	expect(t, viewer, "replay")
+	send(t, viewer, "seek", "ply", 7)
+	expect(t, viewer, "replayState")
+	for _, tt := range []struct {
+		typ    string
+		fields []any
+		code   string
+	}{
+		{"step", nil, "replay_end"},
+		{"seek", []any{"ply", 8}, "invalid_ply"},
+		{"seek", []any{"ply", -1}, "invalid_ply"},
+		{"seek", nil, "bad_message"},
+		{"play", []any{"speed", 100}, "invalid_speed"},
+		{"move", []any{"col", 1}, "read_only"},
+	} {
+		send(t, viewer, tt.typ, append(tt.fields, "seq", 3)...)
+		if msg := expect(t, viewer, "error"); msg["code"] != tt.code || msg["seq"] != float64(3) {
+			t.Errorf("%s %v: %v, want %s", tt.typ, tt.fields, msg, tt.code)
+		}
+	}
+
+	// and in a real game, the replay commands are turned away
+	alice, _, _ := pairUp(t, dial, "")
+	send(t, alice, "pause")
+	if msg := expect(t, alice, "error"); msg["code"] != "not_replay" {
+		t.Errorf("pause in a game: %v", msg)
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	for in, want := range map[string]float64{"": 1, "2": 2, "0.25": 0.25, "64": 64, "0.015625": 1.0 / 64} {
+		if got, err := ParseSpeed(in); err != nil || got != want {
+			t.Errorf("ParseSpeed(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"0", "-1", "65", "0.01", "fast", "NaN"} {
+		if _, err := ParseSpeed(in); err == nil {
+			t.Errorf("ParseSpeed(%q) accepted", in)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// Resume tokens are the only way back into a game after a dropped
+// connection. start hands each player one for their seat: "<gameId>.<side>."
+// plus an HMAC of that under TokenSecret, so nobody can make one up for
+// someone else's seat. A token is good for as long as its game lasts.
+
+func newTokenSecret() []byte {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return b
+}
+
+func (m *Manager) resumeToken(gameID, side string) string {
+	payload := gameID + "." + side
+	return payload + "." + m.signResume(payload)
+}
+
+func (m *Manager) signResume(payload string) string {
+	mac := hmac.New(sha256.New, m.TokenSecret)
+	mac.Write([]byte("resume:" + payload)) // kept apart from anything else signed with the secret
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseResumeToken checks token's signature and returns the seat it's for.
+func (m *Manager) parseResumeToken(token string) (gameID, side string, ok bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", "", false
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(m.signResume(payload))) {
+		return "", "", false
+	}
+	gameID, side, _ = strings.Cut(payload, ".")
+	if side != "R" && side != "Y" {
+		return "", "", false
+	}
+	return gameID, side, true
+}
+
+var errGameEnded = protocol.Error{Code: protocol.CodeExpired, Message: "that game has ended"}
+
+// resume puts the holder of token back in their seat.
+func (m *Manager) resume(conn *client, token string) {
+	gameID, side, ok := m.parseResumeToken(token)
+	if !ok {
+		conn.send(protocol.Error{Code: protocol.CodeForbidden, Message: "invalid resume token"})
+		conn.close()
+		return
+	}
+	m.mu.Lock()
+	st := m.active[gameID]
+	m.mu.Unlock()
+	if st == nil || !st.post(func() { m.rejoin(st, conn, side) }) {
+		conn.send(errGameEnded)
+		conn.close()
+	}
+}
+
+// busy turns conn away if username is already seated in a game; only the
+// resume token gets them back there. Caller holds m.mu.
+func (m *Manager) busy(conn *client, username string) bool {
+	if _, ok := m.userToGame[username]; !ok {
+		return false
+	}
+	conn.send(protocol.Error{Code: protocol.CodeInGame, Message: "you're already in a game; reconnect with its resume token"})
+	conn.close()
+	return true
+}
@@ -0,0 +1,63 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResumeToken(t *testing.T) {
+	m := testManager(time.Minute)
+	token := m.resumeToken("g1", "Y")
+	if gameID, side, ok := m.parseResumeToken(token); !ok || gameID != "g1" || side != "Y" {
+		t.Fatalf("parseResumeToken(%q) = %q, %q, %v", token, gameID, side, ok)
+	}
+	other := testManager(time.Minute)
+	for _, bad := range []string{
+		"",
+		"g1.Y",
+		strings.Replace(token, ".Y.", ".R.", 1), // someone else's seat
+		"g2" + token[2:],                        // someone else's game
+		other.resumeToken("g1", "Y"),            // another secret
+		m.resumeToken("g1", "B"),
+	} {
+		if _, _, ok := m.parseResumeToken(bad); ok {
+			t.Errorf("accepted %q", bad)
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+	alice := dial(as("alice"))
+	expect(t, alice, "queued")
+	bob := dial(as("bob"))
+	start := expect(t, alice, "start")
+	expect(t, bob, "start")
+	token := start["resume"].(string)
+
+	_ = alice.Close()
+	expect(t, bob, "info")
+	// a username alone doesn't get you back in
+	again := dial(as("alice"))
+	if msg := expect(t, again, "error"); msg["code"] != "in_game" {
+		t.Errorf("username only: %v", msg)
+	}
+	forged := dial("resume=" + strings.Replace(token, ".R.", ".Y.", 1))
+	if msg := expect(t, forged, "error"); msg["code"] != "forbidden" {
+		t.Errorf("forged token: %v", msg)
+	}
+	alice = dial("resume=" + token)
+	if msg := expect(t, alice, "rejoined"); msg["color"] != "R" || msg["you"] != "alice" {
+		t.Errorf("rejoined: %v", msg)
+	}
+
+	send(t, bob, "resign")
+	expect(t, alice, "gameOver")
+	waitFor(t, "the game to close", func() bool { return len(m.LiveGames()) == 0 })
+	late := dial("resume=" + token)
+	if msg := expect(t, late, "error"); msg["code"] != "expired" {
+		t.Errorf("token for a finished game: %v", msg)
+	}
+}
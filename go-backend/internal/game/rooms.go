@@ -0,0 +1,82 @@
+package game
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+	"github.com/yourname/fourinarow/internal/util"
+)
+
+// roomCreate is the ?room= value that opens a new private room; any other
+// value is taken as the code of a room to join.
+const roomCreate = "create"
+
+// room is a private game waiting for the one person with its code. There's
+// no bot fallback; it just expires if nobody shows up.
+type room struct {
+	lobbyConn
+	code  string
+	opts  joinOpts
+	timer *time.Timer
+}
+
+func (m *Manager) createRoom(conn *client, username string, opts joinOpts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.refuse(conn) || m.busy(conn, username) {
+		return
+	}
+
+	code := util.NewID(6)
+	for m.rooms[code] != nil {
+		code = util.NewID(6)
+	}
+	rm := &room{lobbyConn: lobbyConn{username: username, conn: conn}, code: code, opts: opts}
+	rm.leave = func() {
+		// host left before anyone joined
+		if m.rooms[code] == rm {
+			rm.timer.Stop()
+			delete(m.rooms, code)
+		}
+	}
+	rm.timer = time.AfterFunc(m.RoomExpiry, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.rooms[code] != rm {
+			return
+		}
+		delete(m.rooms, code)
+		conn.send(protocol.Error{Code: protocol.CodeExpired, Message: "room " + code + " expired, nobody joined"})
+		conn.close()
+	})
+	m.rooms[code] = rm
+	go m.lobbyLoop(&rm.lobbyConn)
+	conn.send(protocol.RoomCreated{Code: code, ExpiresIn: int(m.RoomExpiry.Seconds())})
+}
+
+func (m *Manager) joinRoom(conn *client, username, code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.refuse(conn) || m.busy(conn, username) {
+		return
+	}
+
+	code = strings.ToUpper(code)
+	rm, ok := m.rooms[code]
+	if !ok {
+		conn.send(protocol.Error{Code: protocol.CodeNotFound, Message: "room not found or expired"})
+		conn.close()
+		return
+	}
+	if rm.username == username {
+		conn.send(protocol.Error{Code: protocol.CodeForbidden, Message: "you can't join your own room"})
+		conn.close()
+		return
+	}
+	rm.timer.Stop()
+	delete(m.rooms, code)
+	rm.st = m.startGame(playerConn{username: rm.username, conn: rm.conn, side: "R", reading: true},
+		playerConn{username: username, conn: conn, side: "Y"}, rm.opts)
+	rm.side = "R"
+}
@@ -0,0 +1,73 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoomCreateAndJoin(t *testing.T) {
+	m := testManager(time.Minute)
+	hs, host := wsPair(t)
+	m.createRoom(hs, "alice", joinOpts{variant: Variant{Rows: 5, Cols: 6, Connect: 4}, engine: "easy"})
+	created := expect(t, host, "roomCreated")
+	code, _ := created["code"].(string)
+	if len(code) != 6 || created["expiresIn"] != float64(60) {
+		t.Fatalf("roomCreated: %v", created)
+	}
+
+	// codes aren't case-sensitive
+	gs, guest := wsPair(t)
+	m.joinRoom(gs, "bob", strings.ToLower(code))
+	for _, c := range []struct {
+		msg             map[string]any
+		color, opponent string
+	}{{expect(t, host, "start"), "R", "bob"}, {expect(t, guest, "start"), "Y", "alice"}} {
+		if c.msg["color"] != c.color || c.msg["opponent"] != c.opponent || c.msg["cols"] != float64(6) {
+			t.Errorf("start: %v", c.msg)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.rooms) != 0 {
+		t.Errorf("room still open after the game started: %v", m.rooms)
+	}
+}
+
+func TestRoomJoinErrors(t *testing.T) {
+	m := testManager(time.Minute)
+	hs, host := wsPair(t)
+	m.createRoom(hs, "alice", joinOpts{variant: Standard, engine: "easy"})
+	code := expect(t, host, "roomCreated")["code"].(string)
+
+	for _, tt := range []struct {
+		name, user, code, want string
+	}{
+		{"unknown code", "bob", "ZZZZZZ", "not found"},
+		{"own room", "alice", code, "your own room"},
+	} {
+		s, c := wsPair(t)
+		m.joinRoom(s, tt.user, tt.code)
+		if msg := expect(t, c, "error"); !strings.Contains(msg["message"].(string), tt.want) {
+			t.Errorf("%s: %v", tt.name, msg)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rooms[code] == nil || len(m.active) != 0 {
+		t.Errorf("a failed join changed the room or started a game")
+	}
+}
+
+func TestRoomExpires(t *testing.T) {
+	m := testManager(50 * time.Millisecond)
+	hs, host := wsPair(t)
+	m.createRoom(hs, "alice", joinOpts{variant: Standard, engine: "easy"})
+	code := expect(t, host, "roomCreated")["code"].(string)
+	if msg := expect(t, host, "error"); !strings.Contains(msg["message"].(string), "expired") {
+		t.Fatalf("host told %v", msg)
+	}
+	s, c := wsPair(t)
+	m.joinRoom(s, "bob", code)
+	expect(t, c, "error")
+}
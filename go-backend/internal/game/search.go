@@ -0,0 +1,374 @@
+package game
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Scores are from the point of view of the side to move. A win is reported
+// as winScore minus the number of plies it takes, so faster wins score
+// higher and anything beyond mateBound is a forced result.
+const (
+	winScore  = 1 << 20
+	mateBound = winScore - 1000
+	infScore  = winScore + 1
+)
+
+type ttFlag uint8
+
+const (
+	ttExact ttFlag = iota
+	ttLower
+	ttUpper
+)
+
+type ttKey struct {
+	r, y bitset
+}
+
+func (k ttKey) hash() uint64 {
+	h := k.r.lo*0x9e3779b97f4a7c15 ^ k.r.hi*0xbf58476d1ce4e5b9 ^ k.y.lo*0x94d049bb133111eb ^ k.y.hi*0xd6e8feb86659fd93
+	return h ^ h>>31
+}
+
+type ttEntry struct {
+	depth int
+	score int
+	flag  ttFlag
+	best  int
+}
+
+// ttSize is the number of slots in a table, about 12MB of them; a power
+// of two.
+const ttSize = 1 << 18
+
+type ttSlot struct {
+	key   ttKey
+	gen   uint32
+	score int32
+	depth int16
+	best  int8
+	flag  ttFlag
+}
+
+// table is a fixed-size transposition table. Tables are pooled: taking one
+// bumps gen, which empties it without clearing the slots.
+type table struct {
+	gen   uint32
+	slots [ttSize]ttSlot
+}
+
+var tables = sync.Pool{New: func() any { return new(table) }}
+
+func getTable() *table {
+	t := tables.Get().(*table)
+	if t.gen++; t.gen == 0 {
+		clear(t.slots[:])
+		t.gen = 1
+	}
+	return t
+}
+
+func (t *table) get(k ttKey) (ttEntry, bool) {
+	sl := &t.slots[k.hash()&(ttSize-1)]
+	if sl.gen != t.gen || sl.key != k {
+		return ttEntry{}, false
+	}
+	return ttEntry{depth: int(sl.depth), score: int(sl.score), flag: sl.flag, best: int(sl.best)}, true
+}
+
+// put keeps whichever of the two positions sharing a slot was searched
+// deeper in this search, favouring the new one on a tie.
+func (t *table) put(k ttKey, e ttEntry) {
+	sl := &t.slots[k.hash()&(ttSize-1)]
+	if sl.gen == t.gen && sl.key != k && int(sl.depth) > e.depth {
+		return
+	}
+	*sl = ttSlot{key: k, gen: t.gen, score: int32(e.score), depth: int16(e.depth), best: int8(e.best), flag: e.flag}
+}
+
+// SearchResult is what a finished (or timed-out) search settled on.
+type SearchResult struct {
+	Col   int   // best column, -1 if there's no legal move
+	Score int   // from the mover's point of view
+	Depth int   // deepest fully completed iteration
+	PV    []int // principal variation starting with Col
+	Nodes int
+}
+
+// Solved reports whether Score is a forced win or loss.
+func (r SearchResult) Solved() bool {
+	return r.Score > mateBound || r.Score < -mateBound
+}
+
+// MovesToEnd is the number of plies to the forced result, or 0 when the
+// score is only a heuristic.
+func (r SearchResult) MovesToEnd() int {
+	switch {
+	case r.Score > mateBound:
+		return winScore - r.Score
+	case r.Score < -mateBound:
+		return winScore + r.Score
+	}
+	return 0
+}
+
+type searcher struct {
+	ctx      context.Context
+	tt       *table
+	order    []int
+	windows  []bitset
+	deadline time.Time
+	nodes    int
+	aborted  bool
+}
+
+// Search runs iterative-deepening negamax with alpha-beta for player ("R" or
+// "Y") on a copy of g. maxDepth <= 0 means search until the game is solved;
+// budget <= 0 means no time limit. The result of the last completed depth
+// is returned, so a timeout still yields a sensible move.
+func Search(g *GameLogic, player string, maxDepth int, budget time.Duration) SearchResult {
+	return SearchContext(context.Background(), g, player, maxDepth, budget)
+}
+
+// SearchContext is Search that also stops when ctx is done.
+func SearchContext(ctx context.Context, g *GameLogic, player string, maxDepth int, budget time.Duration) SearchResult {
+	side := sideIndex(player)
+	res := SearchResult{Col: -1}
+	if side < 0 {
+		return res
+	}
+	pos := g.Clone()
+	s := &searcher{
+		ctx:     ctx,
+		tt:      getTable(),
+		order:   CenterOrder(pos.Cols),
+		windows: windowsFor(pos.Variant()),
+	}
+	defer tables.Put(s.tt)
+	if budget > 0 {
+		s.deadline = time.Now().Add(budget)
+	}
+	for _, c := range s.order {
+		if pos.ValidColumn(c) {
+			res.Col = c
+			break
+		}
+	}
+	empty := pos.Rows*pos.Cols - pos.moves
+	if maxDepth <= 0 || maxDepth > empty {
+		maxDepth = empty
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		score := s.negamax(pos, depth, -infScore, infScore, 0, side)
+		if s.aborted {
+			break
+		}
+		res.Score, res.Depth = score, depth
+		res.PV = s.principalVariation(pos, side, depth)
+		if len(res.PV) > 0 {
+			res.Col = res.PV[0]
+		}
+		if res.Solved() {
+			break
+		}
+	}
+	res.Nodes = s.nodes
+	return res
+}
+
+func (s *searcher) timeUp() bool {
+	if s.aborted {
+		return true
+	}
+	if s.nodes&1023 == 0 {
+		s.aborted = s.ctx.Err() != nil || (!s.deadline.IsZero() && time.Now().After(s.deadline))
+	}
+	return s.aborted
+}
+
+func (s *searcher) negamax(g *GameLogic, depth, alpha, beta, ply, side int) int {
+	s.nodes++
+	if s.timeUp() {
+		return 0
+	}
+	if g.IsFull() {
+		return 0
+	}
+	// take an immediate win without searching anything else
+	for _, c := range s.order {
+		if !g.ValidColumn(c) {
+			continue
+		}
+		g.play(c, side)
+		won := g.wins(side)
+		g.undo(c)
+		if won {
+			return winScore - ply - 1
+		}
+	}
+	if depth == 0 {
+		return s.evaluate(g, side)
+	}
+
+	key := ttKey{g.discs[0], g.discs[1]}
+	alphaOrig := alpha
+	hint := -1
+	if e, ok := s.tt.get(key); ok {
+		hint = e.best
+		if e.depth >= depth {
+			score := fromTT(e.score, ply)
+			switch {
+			case e.flag == ttExact:
+				return score
+			case e.flag == ttLower && score > alpha:
+				alpha = score
+			case e.flag == ttUpper && score < beta:
+				beta = score
+			}
+			if alpha >= beta {
+				return score
+			}
+		}
+	}
+
+	best, bestCol := -infScore, -1
+	for i := -1; i < len(s.order); i++ {
+		// the table's best move first, then centre-out
+		c := hint
+		if i >= 0 {
+			c = s.order[i]
+			if c == hint {
+				continue
+			}
+		}
+		if c < 0 || !g.ValidColumn(c) {
+			continue
+		}
+		g.play(c, side)
+		score := -s.negamax(g, depth-1, -beta, -alpha, ply+1, 1-side)
+		g.undo(c)
+		if s.aborted {
+			return 0
+		}
+		if score > best {
+			best, bestCol = score, c
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	e := ttEntry{depth: depth, score: toTT(best, ply), best: bestCol, flag: ttExact}
+	switch {
+	case best <= alphaOrig:
+		e.flag = ttUpper
+	case best >= beta:
+		e.flag = ttLower
+	}
+	s.tt.put(key, e)
+	return best
+}
+
+// Forced-result scores are stored relative to the node rather than the root
+// so an entry stays valid when the same position is reached at another ply.
+func toTT(score, ply int) int {
+	switch {
+	case score > mateBound:
+		return score + ply
+	case score < -mateBound:
+		return score - ply
+	}
+	return score
+}
+
+func fromTT(score, ply int) int {
+	switch {
+	case score > mateBound:
+		return score - ply
+	case score < -mateBound:
+		return score + ply
+	}
+	return score
+}
+
+func (s *searcher) principalVariation(g *GameLogic, side, depth int) []int {
+	pos := g.Clone()
+	var pv []int
+	for len(pv) < depth {
+		e, ok := s.tt.get(ttKey{pos.discs[0], pos.discs[1]})
+		if !ok || e.best < 0 || !pos.ValidColumn(e.best) {
+			break
+		}
+		pv = append(pv, e.best)
+		pos.play(e.best, side)
+		if pos.wins(side) || pos.IsFull() {
+			return pv
+		}
+		side = 1 - side
+	}
+	// a winning last ply is found by the immediate-win scan and never
+	// stored, so fill it in
+	for _, c := range s.order {
+		if !pos.ValidColumn(c) {
+			continue
+		}
+		pos.play(c, side)
+		won := pos.wins(side)
+		pos.undo(c)
+		if won {
+			return append(pv, c)
+		}
+	}
+	return pv
+}
+
+// evaluate scores every open window (Connect cells in a line) that only one
+// side occupies; more discs in the window weigh quadratically more.
+func (s *searcher) evaluate(g *GameLogic, side int) int {
+	mine, theirs := g.discs[side], g.discs[1-side]
+	score := 0
+	for _, w := range s.windows {
+		a, b := w.and(mine).count(), w.and(theirs).count()
+		switch {
+		case a > 0 && b == 0:
+			score += a * a
+		case b > 0 && a == 0:
+			score -= b * b
+		}
+	}
+	return score
+}
+
+var windowCache sync.Map // Variant -> []bitset
+
+// windowsFor lists every line of Connect cells on the board as a mask.
+func windowsFor(v Variant) []bitset {
+	if w, ok := windowCache.Load(v); ok {
+		return w.([]bitset)
+	}
+	var out []bitset
+	h := v.Rows + 1
+	for c := 0; c < v.Cols; c++ {
+		for r := 0; r < v.Rows; r++ { // r counts from the bottom here
+			for _, d := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+				endC, endR := c+d[0]*(v.Connect-1), r+d[1]*(v.Connect-1)
+				if endC >= v.Cols || endR < 0 || endR >= v.Rows {
+					continue
+				}
+				var w bitset
+				for i := 0; i < v.Connect; i++ {
+					w = w.or(bitAt((c+d[0]*i)*h + r + d[1]*i))
+				}
+				out = append(out, w)
+			}
+		}
+	}
+	windowCache.Store(v, out)
+	return out
+}
@@ -0,0 +1,178 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// playMoves is PlayMoves for positions a test knows are fine.
+func playMoves(t *testing.T, v Variant, moves []int) *GameLogic {
+	t.Helper()
+	g, err := PlayMoves(v, moves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestSearchForcedResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		moves  []int
+		plies  int  // to the end with best play
+		winner bool // the side to move wins, rather than loses
+	}{
+		{"win in 1", []int{0, 0, 1, 1, 2, 2}, 1, true},
+		{"win in 3, open three", []int{2, 2, 3, 3}, 3, true},
+		{"lose in 2, facing an open three", []int{2, 2, 3, 3, 4}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := playMoves(t, Standard, tt.moves)
+			mover := sideToMove(g.MoveCount())
+			res := Search(g, mover, 0, 10*time.Second)
+			if !res.Solved() {
+				t.Fatalf("not solved: %+v", res)
+			}
+			if got := res.Score > 0; got != tt.winner {
+				t.Fatalf("score %d, want a %s", res.Score, map[bool]string{true: "win", false: "loss"}[tt.winner])
+			}
+			if got := res.MovesToEnd(); got != tt.plies {
+				t.Fatalf("MovesToEnd() = %d, want %d (%+v)", got, tt.plies, res)
+			}
+			if res.PV[0] != res.Col {
+				t.Fatalf("PV %v doesn't start with Col %d", res.PV, res.Col)
+			}
+
+			// the principal variation is the forced line: it ends the game
+			// on its last disc, with the right side connecting
+			pos := g.Clone()
+			for i, col := range res.PV {
+				p := sideToMove(g.MoveCount() + i)
+				if _, ok := pos.DropDisc(col, p); !ok {
+					t.Fatalf("PV %v: move %d in a full column", res.PV, i+1)
+				}
+				if pos.CheckWinner(p) && i != len(res.PV)-1 {
+					t.Fatalf("PV %v: won early on move %d", res.PV, i+1)
+				}
+			}
+			winner := sideToMove(g.MoveCount() + tt.plies - 1)
+			if len(res.PV) != tt.plies || !pos.CheckWinner(winner) {
+				t.Fatalf("PV %v doesn't end with %s connecting", res.PV, winner)
+			}
+		})
+	}
+}
+
+// forcesWin reports whether side, to move, can connect within plies plies
+// whatever the other side does, by trying everything.
+func forcesWin(g *GameLogic, side, plies int) bool {
+	if plies <= 0 {
+		return false
+	}
+	for c := 0; c < g.Cols; c++ {
+		if !g.ValidColumn(c) {
+			continue
+		}
+		g.play(c, side)
+		won := g.wins(side) || plies >= 3 && !g.IsFull() && everyReplyLoses(g, side, plies-2)
+		g.undo(c)
+		if won {
+			return true
+		}
+	}
+	return false
+}
+
+func everyReplyLoses(g *GameLogic, side, plies int) bool {
+	for c := 0; c < g.Cols; c++ {
+		if !g.ValidColumn(c) {
+			continue
+		}
+		g.play(c, 1-side)
+		lost := !g.wins(1-side) && forcesWin(g, side, plies)
+		g.undo(c)
+		if !lost {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSearchWinInNMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	found := map[int]int{} // plies -> positions checked
+	for tries := 0; tries < 3000 && found[5] < 10; tries++ {
+		g := NewGame()
+		var moves []int
+		for n := 8 + rng.Intn(16); n > 0; n-- {
+			col, side := rng.Intn(g.Cols), g.moves%2
+			if !g.ValidColumn(col) {
+				continue
+			}
+			g.play(col, side)
+			if g.wins(side) {
+				g.undo(col)
+				continue
+			}
+			moves = append(moves, col)
+		}
+		side := g.moves % 2
+		plies := 0
+		for _, n := range []int{1, 3, 5} {
+			if forcesWin(g, side, n) {
+				plies = n
+				break
+			}
+		}
+		if plies == 0 {
+			continue
+		}
+		found[plies]++
+		res := Search(g, sideToMove(side), 0, 10*time.Second)
+		if res.Score <= mateBound || res.MovesToEnd() != plies {
+			t.Fatalf("%s to move wins in %d after %v, but Search says %+v", sideToMove(side), plies, moves, res)
+		}
+	}
+	if found[1] == 0 || found[3] == 0 || found[5] == 0 {
+		t.Fatalf("too few forced wins to check: %v", found)
+	}
+}
+
+func TestSearchFindsTheOnlyBlock(t *testing.T) {
+	// Y has three on the bottom row; anything but column 3 loses at once
+	g := playMoves(t, Standard, []int{6, 0, 6, 1, 5, 2})
+	for _, depth := range []int{1, 2, 4, 8} {
+		if res := Search(g, "R", depth, 0); res.Col != 3 {
+			t.Errorf("depth %d: played %d, want 3 (%+v)", depth, res.Col, res)
+		}
+	}
+}
+
+func TestSearchSolvesSmallBoards(t *testing.T) {
+	// 4x4 connect 3 is a first-player win; 3x3 connect 3 is a draw
+	for _, tt := range []struct {
+		v    Variant
+		wins bool
+	}{
+		{Variant{Rows: 4, Cols: 4, Connect: 3}, true},
+		{Variant{Rows: 3, Cols: 3, Connect: 3}, false},
+	} {
+		res := Search(playMoves(t, tt.v, nil), "R", 0, 0)
+		if got := res.Score > mateBound; got != tt.wins || res.Score < -mateBound {
+			t.Errorf("%v: %+v, want a win: %v", tt.v, res, tt.wins)
+		}
+	}
+}
+
+func TestSearchStopsOnBudget(t *testing.T) {
+	start := time.Now()
+	res := Search(NewGame(), "R", 0, 100*time.Millisecond)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("took %v on a 100ms budget", took)
+	}
+	if res.Col < 0 || res.Depth == 0 {
+		t.Errorf("no move from a timed-out search: %+v", res)
+	}
+}
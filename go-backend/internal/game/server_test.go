@@ -0,0 +1,128 @@
+package game
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yourname/fourinarow/internal/auth"
+	"github.com/yourname/fourinarow/internal/store"
+)
+
+// testSessions signs the logins of every test manager, so as works for any
+// of them.
+var testSessions = auth.Tokens{Secret: []byte("test sessions"), TTL: time.Hour}
+
+// testManager runs on a memory store and never gives up on anyone, so no
+// game a test leaves behind ends by itself.
+func testManager(roomExpiry time.Duration) *Manager {
+	m := NewManager(store.NewMemoryStore(), int(time.Hour/time.Millisecond), int(time.Hour/time.Millisecond), 0, int(roomExpiry/time.Millisecond), 0)
+	m.Sessions = testSessions
+	return m
+}
+
+// as is the /ws query that logs in as the account holder name.
+func as(name string) string {
+	token, _ := testSessions.Issue(name)
+	return "token=" + token
+}
+
+// serve puts m.HandleWS behind a test server; the returned func connects
+// with the given query string.
+func serve(t *testing.T, m *Manager) func(query string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(m.HandleWS))
+	t.Cleanup(srv.Close)
+	return func(query string) *websocket.Conn {
+		t.Helper()
+		c, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?"+query, nil)
+		if err != nil {
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			t.Fatalf("dial ?%s: %v (HTTP %d)", query, err, status)
+		}
+		t.Cleanup(func() { _ = c.Close() })
+		return c
+	}
+}
+
+// pairUp queues alice then bob with the same extra query and returns
+// their sockets once the game has started.
+func pairUp(t *testing.T, dial func(string) *websocket.Conn, query string) (alice, bob *websocket.Conn, gameID string) {
+	t.Helper()
+	alice = dial(as("alice") + query)
+	expect(t, alice, "queued")
+	bob = dial(as("bob") + query)
+	start := expect(t, alice, "start")
+	expect(t, bob, "start")
+	return alice, bob, start["gameId"].(string)
+}
+
+// send writes msg, a message type with its fields, to c.
+func send(t *testing.T, c *websocket.Conn, typ string, fields ...any) {
+	t.Helper()
+	msg := map[string]any{"type": typ}
+	for i := 0; i+1 < len(fields); i += 2 {
+		msg[fields[i].(string)] = fields[i+1]
+	}
+	if err := c.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// play sends moves from red and yellow in turn, waiting for each update.
+func play(t *testing.T, red, yellow *websocket.Conn, cols ...int) {
+	t.Helper()
+	for i, col := range cols {
+		c := red
+		if i%2 == 1 {
+			c = yellow
+		}
+		send(t, c, "move", "col", col)
+		expect(t, red, "update")
+		expect(t, yellow, "update")
+	}
+}
+
+// wsPair connects a peer socket to a server-side client the test hands to the
+// manager itself.
+func wsPair(t *testing.T) (server *client, peer *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			close(conns)
+			return
+		}
+		conns <- c
+	}))
+	t.Cleanup(srv.Close)
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = peer.Close() })
+	return newClient(<-conns), peer
+}
+
+// expect reads from c until a message of type typ arrives.
+func expect(t *testing.T, c *websocket.Conn, typ string) map[string]any {
+	t.Helper()
+	for {
+		var msg map[string]any
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := c.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s: %v", typ, err)
+		}
+		if msg["type"] == typ {
+			return msg
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/yourname/fourinarow/internal/protocol"
+)
+
+// LiveGame is one entry of the live games list people pick from to watch.
+type LiveGame struct {
+	GameID     string  `json:"gameId"`
+	Player1    string  `json:"player1"`
+	Player2    string  `json:"player2"`
+	Moves      int     `json:"moves"`
+	Variant    Variant `json:"variant"`
+	Spectators int     `json:"spectators"`
+}
+
+// LiveGames lists the games in progress, newest first.
+func (m *Manager) LiveGames() []LiveGame {
+	m.mu.Lock()
+	games := make([]*state, 0, len(m.active))
+	for _, st := range m.active {
+		games = append(games, st)
+	}
+	m.mu.Unlock()
+	// startAt and the usernames never change, the rest has to be asked for
+	sort.Slice(games, func(i, j int) bool { return games[i].startAt.After(games[j].startAt) })
+
+	out := make([]LiveGame, 0, len(games))
+	for _, st := range games {
+		var g LiveGame
+		var over bool
+		ok := st.call(func() {
+			over = st.over
+			g = LiveGame{
+				GameID:     st.gameID,
+				Player1:    st.p1.username,
+				Player2:    st.p2.username,
+				Moves:      len(st.moves),
+				Variant:    st.game.Variant(),
+				Spectators: len(st.spectators),
+			}
+		})
+		if ok && !over {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// spectate attaches a read-only watcher to a live game.
+func (m *Manager) spectate(conn *client, gameID string) {
+	m.mu.Lock()
+	st, ok := m.active[gameID]
+	m.mu.Unlock()
+	if !ok || !st.post(func() { m.watch(st, conn) }) {
+		conn.send(protocol.Error{Code: protocol.CodeNotFound, Message: "game not found or finished"})
+		conn.close()
+	}
+}
+
+// watch adds conn to st's spectators; runs on st's goroutine.
+func (m *Manager) watch(st *state, conn *client) {
+	if st.over {
+		conn.send(protocol.Error{Code: protocol.CodeNotFound, Message: "game not found or finished"})
+		conn.close()
+		return
+	}
+	st.spectators[conn] = struct{}{}
+	conn.send(protocol.Spectate{
+		GameID:  st.gameID,
+		Players: protocol.Players{R: st.p1.username, Y: st.p2.username},
+		Board:   st.game.Board(),
+		Turn:    st.turn,
+		Rows:    st.game.Rows,
+		Cols:    st.game.Cols,
+		Connect: st.game.Connect,
+		Clock:   st.clock.payload(st.turn),
+	})
+	go m.spectateLoop(st, conn)
+}
+
+// spectateLoop turns away whatever a watcher sends and drops it once it
+// leaves.
+func (m *Manager) spectateLoop(st *state, conn *client) {
+	for {
+		msg, err := conn.read()
+		if err != nil {
+			break
+		}
+		conn.send(protocol.Error{Code: protocol.CodeReadOnly, Message: "spectators can't send messages", Seq: protocol.PeekSeq(msg)})
+	}
+	st.post(func() { delete(st.spectators, conn) })
+}
+
+// broadcast sends msg to both players and every spectator; runs on st's
+// goroutine.
+func (m *Manager) broadcast(st *state, msg protocol.Message) {
+	st.p1.conn.send(msg)
+	st.p2.conn.send(msg)
+	for conn := range st.spectators {
+		conn.send(msg)
+	}
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpectate(t *testing.T) {
+	m := testManager(time.Minute)
+	dial := serve(t, m)
+	alice, bob, id := pairUp(t, dial, "")
+	play(t, alice, bob, 3, 3)
+
+	watcher := dial("spectate=" + id)
+	msg := expect(t, watcher, "spectate")
+	if players, _ := msg["players"].(map[string]any); players["R"] != "alice" || players["Y"] != "bob" || msg["turn"] != "R" {
+		t.Fatalf("spectate: %v", msg)
+	}
+	live := m.LiveGames()
+	if len(live) != 1 || live[0].GameID != id || live[0].Moves != 2 || live[0].Spectators != 1 {
+		t.Errorf("LiveGames: %+v", live)
+	}
+
+	// watchers can't move
+	if err := watcher.WriteJSON(map[string]any{"type": "move", "col": 0}); err != nil {
+		t.Fatal(err)
+	}
+	play(t, alice, bob, 0, 1)
+	if got := expect(t, watcher, "update")["move"].(map[string]any); got["player"] != "R" {
+		t.Errorf("watcher's first update: %v", got)
+	}
+	expect(t, watcher, "update")
+
+	play(t, alice, bob, 0, 1, 0, 1, 0)
+	if msg := expect(t, watcher, "gameOver"); msg["result"] != "alice wins" {
+		t.Errorf("watcher's gameOver: %v", msg)
+	}
+	waitFor(t, "the game to leave the live list", func() bool { return len(m.LiveGames()) == 0 })
+}
+
+func TestSpectateUnknownGame(t *testing.T) {
+	watcher := serve(t, testManager(time.Minute))("spectate=nope")
+	if msg := expect(t, watcher, "error"); !strings.Contains(msg["message"].(string), "not found") {
+		t.Errorf("spectating nothing: %v", msg)
+	}
+}
@@ -0,0 +1,75 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Variant is the board size and the run length needed to win.
+type Variant struct {
+	Rows    int `json:"rows"`
+	Cols    int `json:"cols"`
+	Connect int `json:"connect"`
+}
+
+// Standard is the classic 6x7 connect four.
+var Standard = Variant{Rows: 6, Cols: 7, Connect: 4}
+
+const maxCols = 16
+
+func (v Variant) Validate() error {
+	if v.Rows < 1 || v.Cols < 1 || v.Cols > maxCols || v.Rows > maxCols {
+		return fmt.Errorf("board must be between 1x1 and %dx%d", maxCols, maxCols)
+	}
+	if (v.Rows+1)*v.Cols > maxBits {
+		return fmt.Errorf("board %dx%d is too large", v.Rows, v.Cols)
+	}
+	if v.Connect < 2 || (v.Connect > v.Rows && v.Connect > v.Cols) {
+		return fmt.Errorf("connect %d does not fit a %dx%d board", v.Connect, v.Rows, v.Cols)
+	}
+	return nil
+}
+
+func (v Variant) String() string {
+	return fmt.Sprintf("%dx%dc%d", v.Rows, v.Cols, v.Connect)
+}
+
+// ParseVariant reads the rows/cols/connect query values; empty values fall
+// back to the Standard ones.
+func ParseVariant(rows, cols, connect string) (Variant, error) {
+	v := Standard
+	for _, f := range []struct {
+		name string
+		raw  string
+		dst  *int
+	}{{"rows", rows, &v.Rows}, {"cols", cols, &v.Cols}, {"connect", connect, &v.Connect}} {
+		if f.raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(f.raw)
+		if err != nil {
+			return v, fmt.Errorf("invalid %s: %q", f.name, f.raw)
+		}
+		*f.dst = n
+	}
+	return v, v.Validate()
+}
+
+// CenterOrder lists the columns from the middle outwards (ties go to the
+// left), which is the usual move ordering for both the bot and auto-play.
+func CenterOrder(cols int) []int {
+	order := make([]int, cols)
+	for c := range order {
+		order[c] = c
+	}
+	dist := func(c int) int {
+		d := 2*c - (cols - 1)
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	sort.SliceStable(order, func(i, j int) bool { return dist(order[i]) < dist(order[j]) })
+	return order
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseVariant(t *testing.T) {
+	tests := []struct {
+		rows, cols, connect string
+		want                Variant
+		err                 string
+	}{
+		{"", "", "", Standard, ""},
+		{"8", "9", "5", Variant{Rows: 8, Cols: 9, Connect: 5}, ""},
+		{"", "", "3", Variant{Rows: 6, Cols: 7, Connect: 3}, ""},
+		{"1", "16", "4", Variant{Rows: 1, Cols: 16, Connect: 4}, ""},
+		{"six", "", "", Variant{}, "invalid rows"},
+		{"0", "", "", Variant{}, "between 1x1"},
+		{"", "17", "", Variant{}, "between 1x1"},
+		{"16", "16", "", Variant{}, "too large"},
+		{"", "", "1", Variant{}, "does not fit"},
+		{"4", "4", "5", Variant{}, "does not fit"},
+	}
+	for _, tt := range tests {
+		v, err := ParseVariant(tt.rows, tt.cols, tt.connect)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseVariant(%q, %q, %q): error %v, want %q", tt.rows, tt.cols, tt.connect, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || v != tt.want {
+			t.Errorf("ParseVariant(%q, %q, %q) = %v, %v; want %v", tt.rows, tt.cols, tt.connect, v, err, tt.want)
+		}
+	}
+}
+
+func TestCenterOrder(t *testing.T) {
+	for cols, want := range map[int][]int{
+		1: {0},
+		4: {1, 2, 0, 3},
+		7: {3, 2, 4, 1, 5, 0, 6},
+	} {
+		if got := CenterOrder(cols); !slices.Equal(got, want) {
+			t.Errorf("CenterOrder(%d) = %v, want %v", cols, got, want)
+		}
+	}
+}
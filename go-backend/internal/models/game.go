@@ -10,12 +10,79 @@ type Move struct {
 }
 
 type GameDoc struct {
-	GameID     string        `bson:"gameId" json:"gameId"`
-	Player1    string        `bson:"player1" json:"player1"`
-	Player2    string        `bson:"player2" json:"player2"`
-	Winner     string        `bson:"winner" json:"winner"` // username or "Draw" or "Forfeit:<winner>"
-	Duration   int           `bson:"duration" json:"duration"` // seconds
-	FinalBoard [][]*string   `bson:"finalBoard" json:"finalBoard"`
-	Moves      []Move        `bson:"moves" json:"moves"`
-	CreatedAt  time.Time     `bson:"createdAt" json:"createdAt"`
+	GameID      string         `bson:"gameId" json:"gameId"`
+	Player1     string         `bson:"player1" json:"player1"`
+	Player2     string         `bson:"player2" json:"player2"`
+	Winner      string         `bson:"winner" json:"winner"`                     // username or "Draw"
+	Reason      string         `bson:"reason,omitempty" json:"reason,omitempty"` // connect, resign, timeout, ...
+	Duration    int            `bson:"duration" json:"duration"`                 // seconds
+	FinalBoard  [][]*string    `bson:"finalBoard" json:"finalBoard"`
+	Moves       []Move         `bson:"moves" json:"moves"`
+	Rated       bool           `bson:"rated" json:"rated"`
+	Ratings     []RatingChange `bson:"ratings,omitempty" json:"ratings,omitempty"`
+	TimeControl string         `bson:"timeControl,omitempty" json:"timeControl,omitempty"` // "60+2", "15/move" or empty
+	Rows        int            `bson:"rows,omitempty" json:"rows,omitempty"`               // the variant; unset on games stored before it was kept
+	Cols        int            `bson:"cols,omitempty" json:"cols,omitempty"`
+	Connect     int            `bson:"connect,omitempty" json:"connect,omitempty"`
+	Hints       int            `bson:"hints,omitempty" json:"hints,omitempty"` // taken by either player; any makes the game unrated
+	Notes       []MoveNote     `bson:"notes,omitempty" json:"notes,omitempty"` // one per move, added after the game by the annotator
+	CreatedAt   time.Time      `bson:"createdAt" json:"createdAt"`
+}
+
+// How the engine rated a move, from a move as good as any to one that threw
+// away the result.
+const (
+	NoteBest       = "best"
+	NoteInaccuracy = "inaccuracy"
+	NoteMistake    = "mistake"
+	NoteBlunder    = "blunder"
+	NoteUnknown    = "unknown" // the engine couldn't compare it with its choice; not tallied
+)
+
+// MoveNote is the engine's verdict on one move of a finished game.
+type MoveNote struct {
+	Class string `bson:"class" json:"class"` // NoteBest, NoteInaccuracy, ...
+	Best  int    `bson:"best" json:"best"`   // the column the engine preferred
+}
+
+// RatingChange is one player's rating before and after a rated game.
+type RatingChange struct {
+	Username string  `bson:"username" json:"username"`
+	Before   float64 `bson:"before" json:"before"`
+	After    float64 `bson:"after" json:"after"`
+	RDBefore float64 `bson:"rdBefore" json:"rdBefore"`
+	RDAfter  float64 `bson:"rdAfter" json:"rdAfter"`
+}
+
+// ActiveGame is a checkpoint of a game still being played, saved after
+// every move so the game can carry on after a server restart. The position
+// is rebuilt from Moves; Board is only there for people looking at the store.
+type ActiveGame struct {
+	GameID      string           `bson:"gameId" json:"gameId"`
+	Player1     string           `bson:"player1" json:"player1"`                   // R
+	Player2     string           `bson:"player2" json:"player2"`                   // Y
+	Engine      string           `bson:"engine,omitempty" json:"engine,omitempty"` // the bot's engine, if one is seated
+	Rows        int              `bson:"rows" json:"rows"`
+	Cols        int              `bson:"cols" json:"cols"`
+	Connect     int              `bson:"connect" json:"connect"`
+	Rated       bool             `bson:"rated" json:"rated"`
+	Turn        string           `bson:"turn" json:"turn"`
+	Board       [][]*string      `bson:"board" json:"board"`
+	Moves       []Move           `bson:"moves" json:"moves"`
+	TimeControl string           `bson:"timeControl,omitempty" json:"timeControl,omitempty"`
+	ClockMs     map[string]int64 `bson:"clockMs,omitempty" json:"clockMs,omitempty"` // "R"/"Y" -> time left
+	Hints       map[string]int   `bson:"hints,omitempty" json:"hints,omitempty"`     // "R"/"Y" -> hints taken
+	StartedAt   time.Time        `bson:"startedAt" json:"startedAt"`
+	UpdatedAt   time.Time        `bson:"updatedAt" json:"updatedAt"`
+}
+
+// HeadToHead is how two players have done against each other.
+type HeadToHead struct {
+	PlayerA    string    `json:"playerA"`
+	PlayerB    string    `json:"playerB"`
+	Games      int       `json:"games"`
+	WinsA      int       `json:"winsA"`
+	WinsB      int       `json:"winsB"`
+	Draws      int       `json:"draws"`
+	LastPlayed time.Time `json:"lastPlayed"`
 }
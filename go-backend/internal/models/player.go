@@ -1,8 +1,68 @@
 package models
 
+import "time"
+
 type Player struct {
 	Username string `bson:"username" json:"username"`
 	Wins     int    `bson:"wins" json:"wins"`
 	Losses   int    `bson:"losses" json:"losses"`
 	Draws    int    `bson:"draws" json:"draws"`
+
+	// Glicko-2; only rated games move these
+	Rating     float64 `bson:"rating" json:"rating"`
+	RD         float64 `bson:"rd" json:"rd"`
+	Volatility float64 `bson:"volatility" json:"volatility"`
+	RatedGames int     `bson:"ratedGames" json:"ratedGames"`
+
+	// the engine's verdicts on their moves, once their games are annotated
+	Annotated MoveTally `bson:"annotated" json:"annotated"`
+}
+
+// MoveTally counts moves by MoveNote class; NoteUnknown isn't counted.
+type MoveTally struct {
+	Best       int `bson:"best" json:"best"`
+	Inaccuracy int `bson:"inaccuracy" json:"inaccuracy"`
+	Mistake    int `bson:"mistake" json:"mistake"`
+	Blunder    int `bson:"blunder" json:"blunder"`
+}
+
+func (t *MoveTally) Add(class string) {
+	switch class {
+	case NoteBest:
+		t.Best++
+	case NoteInaccuracy:
+		t.Inaccuracy++
+	case NoteMistake:
+		t.Mistake++
+	case NoteBlunder:
+		t.Blunder++
+	}
+}
+
+func (t *MoveTally) Merge(o MoveTally) {
+	t.Best += o.Best
+	t.Inaccuracy += o.Inaccuracy
+	t.Mistake += o.Mistake
+	t.Blunder += o.Blunder
+}
+
+func (t MoveTally) Total() int {
+	return t.Best + t.Inaccuracy + t.Mistake + t.Blunder
+}
+
+// Accuracy is the percentage of moves that were best, with inaccuracies
+// counting half; 0 before anything has been annotated.
+func (t MoveTally) Accuracy() float64 {
+	if t.Total() == 0 {
+		return 0
+	}
+	return 100 * (float64(t.Best) + float64(t.Inaccuracy)/2) / float64(t.Total())
+}
+
+// Account is a registered username's login. It's kept apart from Player so
+// the password hash never ends up in anything Player is served as.
+type Account struct {
+	Username     string    `bson:"username" json:"username"`
+	PasswordHash string    `bson:"passwordHash" json:"passwordHash"` // bcrypt
+	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
 }
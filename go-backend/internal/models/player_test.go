@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestMoveTally(t *testing.T) {
+	var tally MoveTally
+	if tally.Accuracy() != 0 {
+		t.Errorf("empty tally: accuracy %v", tally.Accuracy())
+	}
+	for _, class := range []string{NoteBest, NoteBest, NoteInaccuracy, NoteMistake, NoteBlunder, "nonsense"} {
+		tally.Add(class)
+	}
+	if want := (MoveTally{Best: 2, Inaccuracy: 1, Mistake: 1, Blunder: 1}); tally != want {
+		t.Fatalf("tally %+v, want %+v", tally, want)
+	}
+	if got := tally.Accuracy(); got != 50 {
+		t.Errorf("accuracy %v, want 50", got)
+	}
+	tally.Merge(MoveTally{Best: 5})
+	if tally.Total() != 10 || tally.Accuracy() != 75 {
+		t.Errorf("after merging: %+v, accuracy %v", tally, tally.Accuracy())
+	}
+}
@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Error codes.
+const (
+	CodeBadMessage    = "bad_message"    // not JSON, or fields missing / of the wrong type
+	CodeUnknownType   = "unknown_type"   // a "type" this version doesn't have
+	CodeNotInGame     = "not_in_game"    // a game message while still waiting
+	CodeReadOnly      = "read_only"      // spectators can't send anything
+	CodeNotFound      = "not_found"      // no such game or room
+	CodeForbidden     = "forbidden"      // someone else's game or room
+	CodeAlreadyQueued = "already_queued" // the username is already waiting
+	CodeExpired       = "expired"        // a private room nobody joined, or a resume token for a finished game
+	CodeInGame        = "in_game"        // already seated in a game; resume it instead
+	CodeShuttingDown  = "shutting_down"  // the server is restarting; come back shortly
+	CodeNotReplay     = "not_replay"     // a replay command outside a replay
+
+	// rejected game actions
+	CodeNotYourTurn    = "not_your_turn"
+	CodeColumnFull     = "column_full"
+	CodeInvalidColumn  = "invalid_column"   // off the board
+	CodeGameOver       = "game_over"        // too late, the game has ended
+	CodeGameInProgress = "game_in_progress" // a rematch before the game ended
+	CodeNoDrawOffer    = "no_draw_offer"    // accepting or declining nothing
+	CodeAlreadyOffered = "already_offered"
+	CodeDeclined       = "declined"      // the bot won't take a draw
+	CodeOpponentLeft   = "opponent_left" // no one to rematch
+	CodeNoHints        = "no_hints"      // none left this game, or hints are off
+
+	// rejected replay commands
+	CodeInvalidPly   = "invalid_ply"   // a seek before the start or past the end
+	CodeInvalidSpeed = "invalid_speed" // outside 1/64x to 64x
+	CodeReplayEnd    = "replay_end"    // stepping past the last move
+)
+
+// ClientMessage is a message a client sends.
+type ClientMessage interface {
+	Message
+	Sequence() int
+}
+
+// ClientMessages and ServerMessages list every message by direction.
+var (
+	ClientMessages = []ClientMessage{
+		Move{}, Resign{}, OfferDraw{}, AcceptDraw{}, DeclineDraw{}, Rematch{}, Hint{},
+		Pause{}, Play{}, Step{}, Seek{},
+	}
+	ServerMessages = []Message{
+		Hello{}, Queued{}, RoomCreated{}, Start{}, Rejoined{}, Spectate{}, Replay{}, ReplayState{}, Update{}, Suggestion{}, Info{},
+		DrawOffered{}, DrawDeclined{}, RematchOffered{}, GameOver{}, Error{},
+	}
+)
+
+var (
+	clientTypes = byType(ClientMessages)
+	serverTypes = byType(ServerMessages)
+)
+
+func byType[M Message](msgs []M) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, len(msgs))
+	for _, m := range msgs {
+		out[m.MsgType()] = reflect.TypeOf(m)
+	}
+	return out
+}
+
+// DecodeClient parses a frame from a client. The message comes back as a
+// pointer (*Move, *Resign, ...); errors are *Error ready to send back, with
+// the message's seq if it could be read.
+func DecodeClient(data []byte) (ClientMessage, error) {
+	m, err := decode(data, clientTypes)
+	if err != nil {
+		err.(*Error).Seq = PeekSeq(data)
+		return nil, err
+	}
+	return m.(ClientMessage), nil
+}
+
+// PeekSeq digs the seq out of a frame that may not otherwise parse; 0 if
+// there isn't one.
+func PeekSeq(data []byte) int {
+	var s Seq
+	_ = json.Unmarshal(data, &s)
+	return s.Seq
+}
+
+// DecodeServer parses a frame from the server, for clients written in Go.
+func DecodeServer(data []byte) (Message, error) {
+	return decode(data, serverTypes)
+}
+
+func decode(data []byte, types map[string]reflect.Type) (Message, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, &Error{Code: CodeBadMessage, Message: "not a JSON object: " + err.Error()}
+	}
+	if head.Type == "" {
+		return nil, &Error{Code: CodeBadMessage, Message: `missing "type"`}
+	}
+	t, ok := types[head.Type]
+	if !ok {
+		return nil, &Error{Code: CodeUnknownType, Message: fmt.Sprintf("unknown message type %q", head.Type)}
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, &Error{Code: CodeBadMessage, Message: fmt.Sprintf("bad %s message: %v", head.Type, err)}
+	}
+	return v.Interface().(Message), nil
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Message }
+
+// AsError turns err into an Error to send, CodeBadMessage if it isn't one.
+func AsError(err error) Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return *e
+	}
+	return Error{Code: CodeBadMessage, Message: err.Error()}
+}
+
+// UnmarshalJSON insists on a column; a bare {"type":"move"} would otherwise
+// quietly play column 0.
+func (m *Move) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Col *int `json:"col"`
+		Seq
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw.Col == nil {
+		return errors.New(`"col" is required`)
+	}
+	m.Col, m.Seq = *raw.Col, raw.Seq
+	return nil
+}
+
+// UnmarshalJSON insists on a ply, for the same reason as Move.
+func (m *Seek) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Ply *int `json:"ply"`
+		Seq
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if raw.Ply == nil {
+		return errors.New(`"ply" is required`)
+	}
+	m.Ply, m.Seq = *raw.Ply, raw.Seq
+	return nil
+}
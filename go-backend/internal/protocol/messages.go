@@ -0,0 +1,423 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Message types, the value of the "type" field.
+const (
+	// client -> server
+	TypeMove        = "move"
+	TypeResign      = "resign"
+	TypeOfferDraw   = "offerDraw"
+	TypeAcceptDraw  = "acceptDraw"
+	TypeDeclineDraw = "declineDraw"
+	TypeRematch     = "rematch"
+	TypeHint        = "hint"
+	TypePause       = "pause"
+	TypePlay        = "play"
+	TypeStep        = "step"
+	TypeSeek        = "seek"
+
+	// server -> client
+	TypeHello          = "hello"
+	TypeQueued         = "queued"
+	TypeRoomCreated    = "roomCreated"
+	TypeStart          = "start"
+	TypeRejoined       = "rejoined"
+	TypeSpectate       = "spectate"
+	TypeSuggestion     = "suggestion"
+	TypeReplay         = "replay"
+	TypeReplayState    = "replayState"
+	TypeUpdate         = "update"
+	TypeInfo           = "info"
+	TypeDrawOffered    = "drawOffered"
+	TypeDrawDeclined   = "drawDeclined"
+	TypeRematchOffered = "rematchOffered"
+	TypeGameOver       = "gameOver"
+	TypeError          = "error"
+)
+
+// Board is the grid top row first; each cell is "R", "Y" or null.
+type Board [][]*string
+
+// ---- client -> server ----
+
+// Seq is an optional number the client picks for each message it sends.
+// It's echoed back on the Error if the message is rejected, so the client
+// knows which one failed.
+type Seq struct {
+	Seq int `json:"seq,omitempty"`
+}
+
+func (s Seq) Sequence() int { return s.Seq }
+
+// Move drops a disc in column Col (0-based).
+type Move struct {
+	Col int `json:"col"`
+	Seq
+}
+
+// Resign gives up the game.
+type Resign struct{ Seq }
+
+// OfferDraw offers the opponent a draw; it lapses if they move instead.
+type OfferDraw struct{ Seq }
+
+// AcceptDraw takes the opponent's open draw offer.
+type AcceptDraw struct{ Seq }
+
+// DeclineDraw turns the opponent's draw offer down.
+type DeclineDraw struct{ Seq }
+
+// Rematch asks for another game against the same opponent, colours
+// swapped. It starts once both have asked.
+type Rematch struct{ Seq }
+
+// Hint asks the engine what to play; only the asker sees the Suggestion.
+// It's for your own turn, there are Start.Hints of them, and the first one
+// makes a rated game unrated.
+type Hint struct{ Seq }
+
+// Pause, Play, Step and Seek drive a replay (/ws?replay=<gameId>).
+
+// Pause stops a replay where it is.
+type Pause struct{ Seq }
+
+// Play carries on from the current move, at Speed times the original pace
+// if given.
+type Play struct {
+	Speed float64 `json:"speed,omitempty"`
+	Seq
+}
+
+// Step pauses and plays the next move.
+type Step struct{ Seq }
+
+// Seek jumps to the position after Ply moves; 0 is the empty board.
+type Seek struct {
+	Ply int `json:"ply"`
+	Seq
+}
+
+// ---- server -> client ----
+
+// Hello is the first message on every connection.
+type Hello struct {
+	Version int `json:"version"`
+}
+
+// Queued is sent while waiting for an opponent.
+type Queued struct {
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+	Waiting  int    `json:"waiting"`
+}
+
+// RoomCreated carries the code to share for a private room.
+type RoomCreated struct {
+	Code      string `json:"code"`
+	ExpiresIn int    `json:"expiresIn"` // seconds
+}
+
+// Clock is each side's time left in milliseconds, for timed games.
+type Clock struct {
+	R           int64  `json:"R"`
+	Y           int64  `json:"Y"`
+	TimeControl string `json:"timeControl"`
+}
+
+// Start begins a game.
+type Start struct {
+	GameID   string `json:"gameId"`
+	Color    string `json:"color"`
+	You      string `json:"you"` // your name here, e.g. guest-<name>
+	Opponent string `json:"opponent"`
+	Board    Board  `json:"board"`
+	Turn     string `json:"turn"`
+	Rows     int    `json:"rows"`
+	Cols     int    `json:"cols"`
+	Connect  int    `json:"connect"`
+	Clock    *Clock `json:"clock,omitempty"`
+	Resume   string `json:"resume"` // /ws?resume=<this> gets you back in after a drop
+	Rated    bool   `json:"rated"`  // false for guests, bot games (usually), ?rated=false and after a hint
+	Hints    int    `json:"hints"`  // how many more you can ask for
+}
+
+// Rejoined puts a reconnecting player back in their game.
+type Rejoined Start
+
+// Players is who sits on each side.
+type Players struct {
+	R string `json:"R"`
+	Y string `json:"Y"`
+}
+
+// Spectate is the current position, sent to a new watcher.
+type Spectate struct {
+	GameID  string  `json:"gameId"`
+	Players Players `json:"players"`
+	Board   Board   `json:"board"`
+	Turn    string  `json:"turn"`
+	Rows    int     `json:"rows"`
+	Cols    int     `json:"cols"`
+	Connect int     `json:"connect"`
+	Clock   *Clock  `json:"clock,omitempty"`
+}
+
+// Replay opens a replay of a finished game. Moves are then streamed as
+// Update, with a GameOver after the last one.
+type Replay struct {
+	GameID  string  `json:"gameId"`
+	Players Players `json:"players"`
+	Rows    int     `json:"rows"`
+	Cols    int     `json:"cols"`
+	Connect int     `json:"connect"`
+	Moves   int     `json:"moves"` // how many there are to play
+	Speed   float64 `json:"speed"`
+	Playing bool    `json:"playing"`
+}
+
+// ReplayState answers every replay command with where the replay is now.
+type ReplayState struct {
+	Ply     int     `json:"ply"` // moves on the board
+	Board   Board   `json:"board"`
+	Turn    string  `json:"turn"`
+	Speed   float64 `json:"speed"`
+	Playing bool    `json:"playing"`
+}
+
+// Played is the move an Update reports.
+type Played struct {
+	Row    int    `json:"row"` // from the top
+	Col    int    `json:"col"`
+	Player string `json:"player"`
+}
+
+// Update follows every move.
+type Update struct {
+	Move  Played `json:"move"`
+	Board Board  `json:"board"`
+	Turn  string `json:"turn"`
+	Clock *Clock `json:"clock,omitempty"`
+}
+
+// Suggestion answers a Hint.
+type Suggestion struct {
+	Col       int `json:"col"`
+	HintsLeft int `json:"hintsLeft"`
+}
+
+// Info is a human-readable notice.
+type Info struct {
+	Message string `json:"message"`
+}
+
+// DrawOffered, DrawDeclined and RematchOffered go to both players; By is
+// the one who did it.
+type DrawOffered struct {
+	By string `json:"by"`
+}
+
+type DrawDeclined struct {
+	By string `json:"by"`
+}
+
+type RematchOffered struct {
+	By string `json:"by"`
+}
+
+// GameOver ends a game. Result is "<username> wins" or "Draw".
+type GameOver struct {
+	Result string `json:"result"`
+	Reason string `json:"reason"` // connect, boardFull, resign, drawAgreed, timeout, abandoned, engineFailure
+}
+
+// Error reports a message the server couldn't act on, or a connection it
+// won't serve (the socket is closed after those). Seq is the rejected
+// message's, if it had one.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Seq     int    `json:"seq,omitempty"`
+}
+
+func (Move) MsgType() string           { return TypeMove }
+func (Resign) MsgType() string         { return TypeResign }
+func (OfferDraw) MsgType() string      { return TypeOfferDraw }
+func (AcceptDraw) MsgType() string     { return TypeAcceptDraw }
+func (DeclineDraw) MsgType() string    { return TypeDeclineDraw }
+func (Rematch) MsgType() string        { return TypeRematch }
+func (Pause) MsgType() string          { return TypePause }
+func (Play) MsgType() string           { return TypePlay }
+func (Step) MsgType() string           { return TypeStep }
+func (Seek) MsgType() string           { return TypeSeek }
+func (Hint) MsgType() string           { return TypeHint }
+func (Hello) MsgType() string          { return TypeHello }
+func (Queued) MsgType() string         { return TypeQueued }
+func (RoomCreated) MsgType() string    { return TypeRoomCreated }
+func (Start) MsgType() string          { return TypeStart }
+func (Rejoined) MsgType() string       { return TypeRejoined }
+func (Spectate) MsgType() string       { return TypeSpectate }
+func (Replay) MsgType() string         { return TypeReplay }
+func (ReplayState) MsgType() string    { return TypeReplayState }
+func (Update) MsgType() string         { return TypeUpdate }
+func (Suggestion) MsgType() string     { return TypeSuggestion }
+func (Info) MsgType() string           { return TypeInfo }
+func (DrawOffered) MsgType() string    { return TypeDrawOffered }
+func (DrawDeclined) MsgType() string   { return TypeDrawDeclined }
+func (RematchOffered) MsgType() string { return TypeRematchOffered }
+func (GameOver) MsgType() string       { return TypeGameOver }
+func (Error) MsgType() string          { return TypeError }
+
+// The MarshalJSON methods add the "type" field; plain is the same struct
+// without the method so it doesn't recurse.
+
+func (m Move) MarshalJSON() ([]byte, error) {
+	type plain Move
+	return withType(m, plain(m))
+}
+
+func (m Resign) MarshalJSON() ([]byte, error) {
+	type plain Resign
+	return withType(m, plain(m))
+}
+
+func (m OfferDraw) MarshalJSON() ([]byte, error) {
+	type plain OfferDraw
+	return withType(m, plain(m))
+}
+
+func (m AcceptDraw) MarshalJSON() ([]byte, error) {
+	type plain AcceptDraw
+	return withType(m, plain(m))
+}
+
+func (m DeclineDraw) MarshalJSON() ([]byte, error) {
+	type plain DeclineDraw
+	return withType(m, plain(m))
+}
+
+func (m Rematch) MarshalJSON() ([]byte, error) {
+	type plain Rematch
+	return withType(m, plain(m))
+}
+
+func (m Hint) MarshalJSON() ([]byte, error) {
+	type plain Hint
+	return withType(m, plain(m))
+}
+
+func (m Pause) MarshalJSON() ([]byte, error) {
+	type plain Pause
+	return withType(m, plain(m))
+}
+
+func (m Play) MarshalJSON() ([]byte, error) {
+	type plain Play
+	return withType(m, plain(m))
+}
+
+func (m Step) MarshalJSON() ([]byte, error) {
+	type plain Step
+	return withType(m, plain(m))
+}
+
+func (m Seek) MarshalJSON() ([]byte, error) {
+	type plain Seek
+	return withType(m, plain(m))
+}
+
+func (m Hello) MarshalJSON() ([]byte, error) {
+	type plain Hello
+	return withType(m, plain(m))
+}
+
+func (m Queued) MarshalJSON() ([]byte, error) {
+	type plain Queued
+	return withType(m, plain(m))
+}
+
+func (m RoomCreated) MarshalJSON() ([]byte, error) {
+	type plain RoomCreated
+	return withType(m, plain(m))
+}
+
+func (m Start) MarshalJSON() ([]byte, error) {
+	type plain Start
+	return withType(m, plain(m))
+}
+
+func (m Rejoined) MarshalJSON() ([]byte, error) {
+	type plain Rejoined
+	return withType(m, plain(m))
+}
+
+func (m Spectate) MarshalJSON() ([]byte, error) {
+	type plain Spectate
+	return withType(m, plain(m))
+}
+
+func (m Replay) MarshalJSON() ([]byte, error) {
+	type plain Replay
+	return withType(m, plain(m))
+}
+
+func (m ReplayState) MarshalJSON() ([]byte, error) {
+	type plain ReplayState
+	return withType(m, plain(m))
+}
+
+func (m Update) MarshalJSON() ([]byte, error) {
+	type plain Update
+	return withType(m, plain(m))
+}
+
+func (m Suggestion) MarshalJSON() ([]byte, error) {
+	type plain Suggestion
+	return withType(m, plain(m))
+}
+
+func (m Info) MarshalJSON() ([]byte, error) {
+	type plain Info
+	return withType(m, plain(m))
+}
+
+func (m DrawOffered) MarshalJSON() ([]byte, error) {
+	type plain DrawOffered
+	return withType(m, plain(m))
+}
+
+func (m DrawDeclined) MarshalJSON() ([]byte, error) {
+	type plain DrawDeclined
+	return withType(m, plain(m))
+}
+
+func (m RematchOffered) MarshalJSON() ([]byte, error) {
+	type plain RematchOffered
+	return withType(m, plain(m))
+}
+
+func (m GameOver) MarshalJSON() ([]byte, error) {
+	type plain GameOver
+	return withType(m, plain(m))
+}
+
+func (m Error) MarshalJSON() ([]byte, error) {
+	type plain Error
+	return withType(m, plain(m))
+}
+
+// withType encodes body with m's "type" as the first field.
+func withType(m Message, body any) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(`{"type":`), strconv.Quote(m.MsgType())...)
+	if len(b) > 2 { // not "{}"
+		out = append(out, ',')
+	}
+	return append(out, b[1:]...), nil
+}
@@ -0,0 +1,46 @@
+// Package protocol is the WebSocket protocol spoken on /ws: one JSON object
+// per frame, told apart by its "type" field. Every message either side can
+// send has a struct here; the server, the CLI and (through Schema) the React
+// client all work from these, so they can't drift apart.
+//
+// Clients ask for a version with /ws?v=<n>. The server answers with the
+// highest version it speaks that isn't newer than that, in a Hello before
+// anything else. Leaving v out means the current Version.
+package protocol
+
+//go:generate go run ../../cmd/protocol-schema -o ../../../frontend/src/protocol/schema.json
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// Version is the newest protocol version this build speaks.
+	Version = 1
+	// MinVersion is the oldest one it still accepts.
+	MinVersion = 1
+)
+
+// Message is anything sent over the socket, in either direction.
+type Message interface {
+	MsgType() string
+}
+
+// Negotiate picks the version to speak with a client that asked for v.
+func Negotiate(v string) (int, error) {
+	if v == "" {
+		return Version, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol version %q", v)
+	}
+	if n < MinVersion {
+		return 0, fmt.Errorf("protocol version %d is no longer supported (oldest is %d)", n, MinVersion)
+	}
+	if n > Version {
+		n = Version
+	}
+	return n, nil
+}
@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeClient(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ClientMessage // nil for an error
+		code string
+		seq  int
+	}{
+		{`{"type":"move","col":3}`, &Move{Col: 3}, "", 0},
+		{`{"type":"move","col":3,"seq":5}`, &Move{Col: 3, Seq: Seq{Seq: 5}}, "", 0},
+		{`{"type":"offerDraw","seq":2}`, &OfferDraw{Seq{Seq: 2}}, "", 0},
+		{`{"type":"move","col":0}`, &Move{Col: 0}, "", 0},
+		{`{"type":"resign"}`, &Resign{}, "", 0},
+		{`{"type":"rematch","extra":true}`, &Rematch{}, "", 0},
+		{`{"type":"seek","ply":0,"seq":4}`, &Seek{Ply: 0, Seq: Seq{Seq: 4}}, "", 0},
+		{`{"type":"play","speed":0.5}`, &Play{Speed: 0.5}, "", 0},
+		{`{"type":"hint","seq":7}`, &Hint{Seq{Seq: 7}}, "", 0},
+		{`{"type":"move","seq":3}`, nil, CodeBadMessage, 3},
+		{`{"type":"seek","seq":6}`, nil, CodeBadMessage, 6},
+		{`{"type":"move","col":"3"}`, nil, CodeBadMessage, 0},
+		{`{"col":3,"seq":8}`, nil, CodeBadMessage, 8},
+		{`[1,2]`, nil, CodeBadMessage, 0},
+		{`move 3`, nil, CodeBadMessage, 0},
+		{`{"type":"castle","seq":1}`, nil, CodeUnknownType, 1},
+		{`{"type":"start"}`, nil, CodeUnknownType, 0}, // the server's, not the client's
+	}
+	for _, tt := range tests {
+		got, err := DecodeClient([]byte(tt.in))
+		if tt.want != nil {
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeClient(%s) = %#v, %v; want %#v", tt.in, got, err, tt.want)
+			}
+			continue
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Code != tt.code || e.Seq != tt.seq {
+			t.Errorf("DecodeClient(%s): error %#v, want code %s and seq %d", tt.in, err, tt.code, tt.seq)
+		}
+	}
+}
+
+func TestMessagesRoundTrip(t *testing.T) {
+	r := "R"
+	var examples []Message
+	for _, msg := range ClientMessages {
+		examples = append(examples, msg)
+	}
+	examples = append(examples, ServerMessages...)
+	examples = append(examples,
+		Move{Col: 6, Seq: Seq{Seq: 4}},
+		Resign{Seq{Seq: 9}},
+		Seek{Ply: 7, Seq: Seq{Seq: 2}},
+		Suggestion{Col: 3, HintsLeft: 2},
+		ReplayState{Ply: 1, Board: Board{{nil, &r}}, Turn: "Y", Speed: 2, Playing: true},
+		Update{Move: Played{Row: 5, Col: 3, Player: "R"}, Board: Board{{nil, &r}}, Turn: "Y"},
+		GameOver{Result: "alice wins", Reason: "connect"},
+		Error{Code: CodeNotFound, Message: "no such game"},
+		Error{Code: CodeColumnFull, Message: "column 3 is full", Seq: 12},
+	)
+	for _, msg := range examples {
+		b, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("%T: %v", msg, err)
+		}
+		if !strings.HasPrefix(string(b), `{"type":"`+msg.MsgType()+`"`) {
+			t.Errorf("%T encodes as %s", msg, b)
+		}
+		got, err := DecodeServer(b)
+		if _, ok := clientTypes[msg.MsgType()]; ok {
+			got, err = DecodeClient(b)
+		}
+		if err != nil {
+			t.Errorf("%T: decoding %s: %v", msg, b, err)
+			continue
+		}
+		if back := reflect.ValueOf(got).Elem().Interface(); !reflect.DeepEqual(back, msg) {
+			t.Errorf("%s came back as %#v, want %#v", b, back, msg)
+		}
+	}
+}
+
+func TestAsError(t *testing.T) {
+	_, err := DecodeClient([]byte(`{"type":"nope"}`))
+	if e := AsError(err); e.Code != CodeUnknownType {
+		t.Errorf("AsError(%v) = %+v", err, e)
+	}
+	if e := AsError(errors.New("boom")); e.Code != CodeBadMessage || e.Message != "boom" {
+		t.Errorf("AsError of a plain error = %+v", e)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int
+		ok   bool
+	}{{"", Version, true}, {"1", 1, true}, {"99", Version, true}, {"0", 0, false}, {"one", 0, false}} {
+		got, err := Negotiate(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("Negotiate(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestSchemaCoversEveryMessage(t *testing.T) {
+	b, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Defs map[string]struct {
+			OneOf      []any          `json:"oneOf"`
+			Properties map[string]any `json:"properties"`
+			Required   []string       `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	var client []Message
+	for _, msg := range ClientMessages {
+		client = append(client, msg)
+	}
+	for dir, msgs := range map[string][]Message{"ClientMessage": client, "ServerMessage": ServerMessages} {
+		if n := len(s.Defs[dir].OneOf); n != len(msgs) {
+			t.Errorf("%s has %d members, want %d", dir, n, len(msgs))
+		}
+		for _, msg := range msgs {
+			def, ok := s.Defs[reflect.TypeOf(msg).Name()]
+			if !ok {
+				t.Errorf("no schema for %T", msg)
+				continue
+			}
+			typ, _ := def.Properties["type"].(map[string]any)
+			if typ["const"] != msg.MsgType() || len(def.Required) == 0 || def.Required[0] != "type" {
+				t.Errorf("%T: type %v, required %v", msg, typ, def.Required)
+			}
+		}
+	}
+}
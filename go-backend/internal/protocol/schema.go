@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Schema is a JSON Schema (draft 2020-12) for the protocol, built from the
+// structs in this package. Every message is under $defs by its Go name, and
+// ClientMessage / ServerMessage are the unions for each direction.
+// cmd/protocol-schema writes it out for the React client.
+func Schema() ([]byte, error) {
+	g := schemaGen{defs: map[string]any{}}
+	clients := make([]Message, len(ClientMessages))
+	for i, m := range ClientMessages {
+		clients[i] = m
+	}
+	for _, dir := range []struct {
+		name string
+		msgs []Message
+	}{{"ClientMessage", clients}, {"ServerMessage", ServerMessages}} {
+		var refs []any
+		for _, m := range dir.msgs {
+			t := reflect.TypeOf(m)
+			s := g.object(t)
+			s["properties"].(map[string]any)["type"] = map[string]any{"const": m.MsgType()}
+			s["required"] = append([]string{"type"}, s["required"].([]string)...)
+			g.defs[t.Name()] = s
+			refs = append(refs, ref(t.Name()))
+		}
+		g.defs[dir.name] = map[string]any{"oneOf": refs}
+	}
+	return json.MarshalIndent(map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     fmt.Sprintf("fourinarow-protocol-v%d", Version),
+		"title":   fmt.Sprintf("Four-in-a-Row WebSocket protocol v%d", Version),
+		"anyOf":   []any{ref("ClientMessage"), ref("ServerMessage")},
+		"$defs":   g.defs,
+	}, "", "  ")
+}
+
+type schemaGen struct {
+	defs map[string]any
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	g.fields(t, props, &required)
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
+
+// fields adds t's fields to props, flattening embedded structs the way
+// encoding/json does.
+func (g *schemaGen) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" && f.Anonymous && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, props, required)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omit := strings.Contains(opts, "omitempty")
+		ft := f.Type
+		if omit && ft.Kind() == reflect.Pointer {
+			ft = ft.Elem() // optional rather than nullable
+		}
+		props[name] = g.of(ft)
+		if !omit {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func (g *schemaGen) of(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{g.of(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.of(t.Elem())}
+	case reflect.Struct:
+		// named helper structs (Clock, Players...) get their own $defs entry
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = g.object(t)
+		}
+		return ref(t.Name())
+	}
+	return map[string]any{}
+}
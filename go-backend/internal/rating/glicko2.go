@@ -0,0 +1,97 @@
+// Package rating implements Glicko-2 (Glickman, "Example of the Glicko-2
+// system") for one game at a time: every finished game is its own rating
+// period.
+package rating
+
+import "math"
+
+const (
+	DefaultRating     = 1500.0
+	DefaultRD         = 350.0
+	DefaultVolatility = 0.06
+
+	scale = 173.7178
+	tau   = 0.5 // how fast volatility may change
+	eps   = 0.000001
+	minRD = 30.0 // keep established players from freezing completely
+)
+
+// Rating is a player's Glicko-2 state on the usual 1500 scale.
+type Rating struct {
+	Rating     float64
+	RD         float64
+	Volatility float64
+}
+
+// New is the rating every player starts with.
+func New() Rating {
+	return Rating{Rating: DefaultRating, RD: DefaultRD, Volatility: DefaultVolatility}
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expected(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-g(phiJ)*(mu-muJ)))
+}
+
+// Update returns p's new rating after one game against opp, where score is
+// 1 for a win, 0.5 for a draw and 0 for a loss.
+func Update(p, opp Rating, score float64) Rating {
+	if p.Volatility == 0 {
+		p.Volatility = DefaultVolatility
+	}
+	mu, phi := (p.Rating-DefaultRating)/scale, p.RD/scale
+	muJ, phiJ := (opp.Rating-DefaultRating)/scale, opp.RD/scale
+
+	gJ := g(phiJ)
+	e := expected(mu, muJ, phiJ)
+	v := 1 / (gJ * gJ * e * (1 - e))
+	delta := v * gJ * (score - e)
+
+	sigma := newVolatility(p.Volatility, phi, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phiNew := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	muNew := mu + phiNew*phiNew*gJ*(score-e)
+
+	return Rating{
+		Rating:     muNew*scale + DefaultRating,
+		RD:         math.Max(phiNew*scale, minRD),
+		Volatility: sigma,
+	}
+}
+
+// newVolatility is step 5 of the paper (the Illinois algorithm).
+func newVolatility(sigma, phi, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-d)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > eps {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
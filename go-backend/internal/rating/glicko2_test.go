@@ -0,0 +1,62 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+// The opponents of the example in Glickman's paper, each game rated as a
+// period of its own, worked through the paper's steps by hand.
+func TestUpdate(t *testing.T) {
+	player := Rating{Rating: 1500, RD: 200, Volatility: 0.06}
+	tests := []struct {
+		name   string
+		p, opp Rating
+		score  float64
+		want   Rating
+	}{
+		{"beats a weaker player", player, Rating{Rating: 1400, RD: 30}, 1, Rating{1563.564, 175.403, 0.059999}},
+		{"loses to a stronger one", player, Rating{Rating: 1550, RD: 100}, 0, Rating{1426.686, 175.903, 0.059999}},
+		{"new players draw", New(), New(), 0.5, Rating{1500, 290.319, 0.059999}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Update(tt.p, tt.opp, tt.score)
+			if math.Abs(got.Rating-tt.want.Rating) > 0.001 || math.Abs(got.RD-tt.want.RD) > 0.001 || math.Abs(got.Volatility-tt.want.Volatility) > 0.000001 {
+				t.Errorf("Update = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateIsSymmetric(t *testing.T) {
+	a, b := New(), Rating{Rating: 1620, RD: 80, Volatility: 0.06}
+	for _, score := range []float64{0, 0.5, 1} {
+		// equal uncertainty means equal and opposite moves
+		up, down := Update(a, a, score), Update(a, a, 1-score)
+		if d := (up.Rating - 1500) + (down.Rating - 1500); math.Abs(d) > 1e-9 {
+			t.Errorf("score %v: %v and %v don't mirror", score, up.Rating, down.Rating)
+		}
+	}
+	// an upset moves the underdog further than the favourite's win would
+	upset, expected := Update(a, b, 1).Rating-a.Rating, Update(b, a, 1).Rating-b.Rating
+	if upset <= 0 || expected <= 0 || expected >= upset {
+		t.Errorf("upset gained %v, the favourite's win %v", upset, expected)
+	}
+}
+
+func TestUpdateKeepsAFloorUnderRD(t *testing.T) {
+	p := Rating{Rating: 1500, RD: 31, Volatility: 0.0001}
+	for i := 0; i < 50; i++ {
+		p = Update(p, Rating{Rating: 1500, RD: 30, Volatility: 0.06}, 0.5)
+	}
+	if p.RD != minRD {
+		t.Errorf("RD after 50 games = %v, want the %v floor", p.RD, minRD)
+	}
+}
+
+func TestUpdateDefaultsVolatility(t *testing.T) {
+	if got, want := Update(Rating{Rating: 1500, RD: 350}, New(), 1), Update(New(), New(), 1); got != want {
+		t.Errorf("zero volatility: %+v, want %+v", got, want)
+	}
+}
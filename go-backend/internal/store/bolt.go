@@ -0,0 +1,357 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/rating"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	playersBucket  = []byte("players")
+	gamesBucket    = []byte("games")
+	activeBucket   = []byte("active")
+	accountsBucket = []byte("accounts")
+	// playerGamesBucket indexes games by player, newest last: the key is
+	// username, 0, CreatedAt as big-endian nanoseconds, gameId; the value
+	// is the gameId.
+	playerGamesBucket = []byte("playerGames")
+)
+
+// BoltStore keeps players and games as JSON in a single bbolt file, for
+// self-hosting without a database server. Every method is one bolt
+// transaction, so RateGame updates both players atomically.
+type BoltStore struct {
+	DB *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{playersBucket, gamesBucket, activeBucket, accountsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		if tx.Bucket(playerGamesBucket) != nil {
+			return nil
+		}
+		// a file from before the index: build it from the games so far
+		idx, err := tx.CreateBucket(playerGamesBucket)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(gamesBucket).ForEach(func(k, v []byte) error {
+			var g models.GameDoc
+			if err := json.Unmarshal(v, &g); err != nil {
+				return fmt.Errorf("decode game %s: %w", k, err)
+			}
+			return indexBoltGame(idx, g)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{DB: db}, nil
+}
+
+func (s *BoltStore) Close(ctx context.Context) error {
+	return s.DB.Close()
+}
+
+func getBoltPlayer(tx *bolt.Tx, username string) (models.Player, error) {
+	var p models.Player
+	v := tx.Bucket(playersBucket).Get([]byte(username))
+	if v == nil {
+		return p, fmt.Errorf("get player %s: %w", username, ErrNotFound)
+	}
+	if err := json.Unmarshal(v, &p); err != nil {
+		return p, fmt.Errorf("decode player %s: %w", username, err)
+	}
+	return p, nil
+}
+
+func putBoltPlayer(tx *bolt.Tx, p models.Player) error {
+	v, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(playersBucket).Put([]byte(p.Username), v)
+}
+
+// updatePlayer applies fn to a stored player; unknown players are skipped.
+func (s *BoltStore) updatePlayer(tx *bolt.Tx, username string, fn func(p *models.Player)) error {
+	p, err := getBoltPlayer(tx, username)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fn(&p)
+	return putBoltPlayer(tx, p)
+}
+
+func (s *BoltStore) EnsurePlayer(ctx context.Context, username string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(playersBucket).Get([]byte(username)) != nil {
+			return nil
+		}
+		return putBoltPlayer(tx, newPlayer(username))
+	})
+}
+
+func (s *BoltStore) GetPlayer(ctx context.Context, username string) (models.Player, error) {
+	var p models.Player
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		p, err = getBoltPlayer(tx, username)
+		return err
+	})
+	return p, err
+}
+
+func (s *BoltStore) IncWinLoss(ctx context.Context, winner, loser string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		if err := s.updatePlayer(tx, winner, func(p *models.Player) { p.Wins++ }); err != nil {
+			return err
+		}
+		return s.updatePlayer(tx, loser, func(p *models.Player) { p.Losses++ })
+	})
+}
+
+func (s *BoltStore) IncDraws(ctx context.Context, users []string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		for _, u := range users {
+			if err := s.updatePlayer(tx, u, func(p *models.Player) { p.Draws++ }); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) RateGame(ctx context.Context, p1, p2 string, score1, botRating float64) ([]models.RatingChange, error) {
+	var changes []models.RatingChange
+	err := s.DB.Update(func(tx *bolt.Tx) error {
+		var err error
+		changes, err = rateGame(p1, p2, score1, botRating,
+			func(name string) (models.Player, error) { return getBoltPlayer(tx, name) },
+			func(name string, r rating.Rating) error {
+				return s.updatePlayer(tx, name, func(p *models.Player) {
+					p.Rating, p.RD, p.Volatility = r.Rating, r.RD, r.Volatility
+					p.RatedGames++
+				})
+			})
+		return err
+	})
+	return changes, err
+}
+
+func (s *BoltStore) InsertGame(ctx context.Context, g models.GameDoc) error {
+	g.CreatedAt = time.Now()
+	v, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(gamesBucket).Put([]byte(g.GameID), v); err != nil {
+			return err
+		}
+		return indexBoltGame(tx.Bucket(playerGamesBucket), g)
+	})
+}
+
+func playerGameKey(username string, c GameCursor) []byte {
+	k := make([]byte, 0, len(username)+9+len(c.GameID))
+	k = append(k, username...)
+	k = append(k, 0)
+	k = binary.BigEndian.AppendUint64(k, uint64(c.CreatedAt.UnixNano()))
+	return append(k, c.GameID...)
+}
+
+func indexBoltGame(idx *bolt.Bucket, g models.GameDoc) error {
+	for _, u := range []string{g.Player1, g.Player2} {
+		if err := idx.Put(playerGameKey(u, CursorOf(g)), []byte(g.GameID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) SaveActiveGame(ctx context.Context, g models.ActiveGame) error {
+	g.UpdatedAt = time.Now()
+	v, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(activeBucket).Put([]byte(g.GameID), v)
+	})
+}
+
+func (s *BoltStore) DeleteActiveGame(ctx context.Context, gameID string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(activeBucket).Delete([]byte(gameID))
+	})
+}
+
+func (s *BoltStore) ActiveGames(ctx context.Context) ([]models.ActiveGame, error) {
+	var out []models.ActiveGame
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(activeBucket).ForEach(func(k, v []byte) error {
+			var g models.ActiveGame
+			if err := json.Unmarshal(v, &g); err != nil {
+				return fmt.Errorf("decode active game %s: %w", k, err)
+			}
+			out = append(out, g)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func (s *BoltStore) TopPlayers(ctx context.Context, limit int64, minGames int) ([]models.Player, error) {
+	var all []models.Player
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(playersBucket).ForEach(func(k, v []byte) error {
+			var p models.Player
+			if err := json.Unmarshal(v, &p); err != nil {
+				return fmt.Errorf("decode player %s: %w", k, err)
+			}
+			all = append(all, withRatingDefaults(p))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leaderboard(all, limit, minGames), nil
+}
+
+func (s *BoltStore) CreateAccount(ctx context.Context, a models.Account) error {
+	a.CreatedAt = time.Now()
+	v, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(accountsBucket)
+		if b.Get([]byte(a.Username)) != nil {
+			return fmt.Errorf("create account %s: %w", a.Username, ErrTaken)
+		}
+		return b.Put([]byte(a.Username), v)
+	})
+}
+
+func (s *BoltStore) GetAccount(ctx context.Context, username string) (models.Account, error) {
+	var a models.Account
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(accountsBucket).Get([]byte(username))
+		if v == nil {
+			return fmt.Errorf("get account %s: %w", username, ErrNotFound)
+		}
+		return json.Unmarshal(v, &a)
+	})
+	return a, err
+}
+
+func getBoltGame(tx *bolt.Tx, gameID string) (models.GameDoc, error) {
+	var g models.GameDoc
+	v := tx.Bucket(gamesBucket).Get([]byte(gameID))
+	if v == nil {
+		return g, fmt.Errorf("get game %s: %w", gameID, ErrNotFound)
+	}
+	if err := json.Unmarshal(v, &g); err != nil {
+		return g, fmt.Errorf("decode game %s: %w", gameID, err)
+	}
+	return g, nil
+}
+
+func (s *BoltStore) GetGame(ctx context.Context, gameID string) (models.GameDoc, error) {
+	var g models.GameDoc
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		g, err = getBoltGame(tx, gameID)
+		return err
+	})
+	return g, err
+}
+
+func (s *BoltStore) AnnotateGame(ctx context.Context, gameID string, notes []models.MoveNote) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		g, err := getBoltGame(tx, gameID)
+		if err != nil || g.Notes != nil {
+			return err
+		}
+		g.Notes = notes
+		v, err := json.Marshal(g)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(gamesBucket).Put([]byte(g.GameID), v); err != nil {
+			return err
+		}
+		for name, t := range noteTallies(g, notes) {
+			if err := s.updatePlayer(tx, name, func(p *models.Player) { p.Annotated.Merge(t) }); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PlayerGames walks username's part of the index backwards from q.Before,
+// so a page costs about as much as the games it skips over for filters.
+func (s *BoltStore) PlayerGames(ctx context.Context, username string, q GameQuery) ([]models.GameDoc, error) {
+	var out []models.GameDoc
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		prefix := append([]byte(username), 0)
+		start := append(prefix[:len(prefix):len(prefix)], 0xff) // past all of username's keys
+		if q.Before != nil {
+			start = playerGameKey(username, *q.Before)
+		}
+		c := tx.Bucket(playerGamesBucket).Cursor()
+		// Seek lands on start or the first key after it; we want the one before
+		k, v := c.Seek(start)
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Prev() {
+			g, err := getBoltGame(tx, string(v))
+			if err != nil {
+				return err
+			}
+			if !q.matches(g, username) {
+				continue
+			}
+			out = append(out, g)
+			if q.Limit > 0 && len(out) == q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (s *BoltStore) HeadToHead(ctx context.Context, a, b string) (models.HeadToHead, error) {
+	games, err := s.PlayerGames(ctx, a, GameQuery{Opponent: b})
+	if err != nil {
+		return models.HeadToHead{}, err
+	}
+	return headToHead(a, b, games), nil
+}
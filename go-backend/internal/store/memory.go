@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/rating"
+)
+
+// MemoryStore keeps everything in maps; nothing survives a restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	players  map[string]*models.Player
+	games    []models.GameDoc
+	active   map[string]models.ActiveGame
+	accounts map[string]models.Account
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		players:  make(map[string]*models.Player),
+		active:   make(map[string]models.ActiveGame),
+		accounts: make(map[string]models.Account),
+	}
+}
+
+func (s *MemoryStore) Close(ctx context.Context) error { return nil }
+
+func (s *MemoryStore) EnsurePlayer(ctx context.Context, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.players[username]; !ok {
+		p := newPlayer(username)
+		s.players[username] = &p
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetPlayer(ctx context.Context, username string) (models.Player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.players[username]
+	if !ok {
+		return models.Player{}, fmt.Errorf("get player %s: %w", username, ErrNotFound)
+	}
+	return *p, nil
+}
+
+func (s *MemoryStore) IncWinLoss(ctx context.Context, winner, loser string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.players[winner]; ok {
+		p.Wins++
+	}
+	if p, ok := s.players[loser]; ok {
+		p.Losses++
+	}
+	return nil
+}
+
+func (s *MemoryStore) IncDraws(ctx context.Context, users []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range users {
+		if p, ok := s.players[u]; ok {
+			p.Draws++
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) RateGame(ctx context.Context, p1, p2 string, score1, botRating float64) ([]models.RatingChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return rateGame(p1, p2, score1, botRating,
+		func(name string) (models.Player, error) {
+			p, ok := s.players[name]
+			if !ok {
+				return models.Player{}, fmt.Errorf("get player %s: %w", name, ErrNotFound)
+			}
+			return *p, nil
+		},
+		func(name string, r rating.Rating) error {
+			p := s.players[name]
+			p.Rating, p.RD, p.Volatility = r.Rating, r.RD, r.Volatility
+			p.RatedGames++
+			return nil
+		})
+}
+
+func (s *MemoryStore) InsertGame(ctx context.Context, g models.GameDoc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.CreatedAt = time.Now()
+	s.games = append(s.games, g)
+	return nil
+}
+
+func (s *MemoryStore) SaveActiveGame(ctx context.Context, g models.ActiveGame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.UpdatedAt = time.Now()
+	s.active[g.GameID] = g
+	return nil
+}
+
+func (s *MemoryStore) DeleteActiveGame(ctx context.Context, gameID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, gameID)
+	return nil
+}
+
+func (s *MemoryStore) ActiveGames(ctx context.Context) ([]models.ActiveGame, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]models.ActiveGame, 0, len(s.active))
+	for _, g := range s.active {
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) TopPlayers(ctx context.Context, limit int64, minGames int) ([]models.Player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make([]models.Player, 0, len(s.players))
+	for _, p := range s.players {
+		all = append(all, *p)
+	}
+	return leaderboard(all, limit, minGames), nil
+}
+
+func (s *MemoryStore) CreateAccount(ctx context.Context, a models.Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.accounts[a.Username]; ok {
+		return fmt.Errorf("create account %s: %w", a.Username, ErrTaken)
+	}
+	a.CreatedAt = time.Now()
+	s.accounts[a.Username] = a
+	return nil
+}
+
+func (s *MemoryStore) GetAccount(ctx context.Context, username string) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.accounts[username]
+	if !ok {
+		return a, fmt.Errorf("get account %s: %w", username, ErrNotFound)
+	}
+	return a, nil
+}
+
+func (s *MemoryStore) GetGame(ctx context.Context, gameID string) (models.GameDoc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.games {
+		if g.GameID == gameID {
+			return g, nil
+		}
+	}
+	return models.GameDoc{}, fmt.Errorf("get game %s: %w", gameID, ErrNotFound)
+}
+
+func (s *MemoryStore) AnnotateGame(ctx context.Context, gameID string, notes []models.MoveNote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.games {
+		g := &s.games[i]
+		if g.GameID != gameID {
+			continue
+		}
+		if g.Notes != nil {
+			return nil
+		}
+		g.Notes = notes
+		for name, t := range noteTallies(*g, notes) {
+			if p, ok := s.players[name]; ok {
+				p.Annotated.Merge(t)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("annotate game %s: %w", gameID, ErrNotFound)
+}
+
+func (s *MemoryStore) PlayerGames(ctx context.Context, username string, q GameQuery) ([]models.GameDoc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []models.GameDoc
+	for _, g := range s.games {
+		if q.matches(g, username) {
+			out = append(out, g)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return CursorOf(out[i]).after(out[j]) }) // newest first
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) HeadToHead(ctx context.Context, a, b string) (models.HeadToHead, error) {
+	games, err := s.PlayerGames(ctx, a, GameQuery{Opponent: b})
+	if err != nil {
+		return models.HeadToHead{}, err
+	}
+	return headToHead(a, b, games), nil
+}
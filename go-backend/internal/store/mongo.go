@@ -2,32 +2,75 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
-	"fmt"   
+
 	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/rating"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoStore struct {
-	Client     *mongo.Client
-	DB         *mongo.Database
-	PlayersCol *mongo.Collection
-	GamesCol   *mongo.Collection
+	Client      *mongo.Client
+	DB          *mongo.Database
+	PlayersCol  *mongo.Collection
+	GamesCol    *mongo.Collection
+	ActiveCol   *mongo.Collection // games in progress, see SaveActiveGame
+	AccountsCol *mongo.Collection // logins, see CreateAccount
 }
 
 func NewMongoStore(ctx context.Context, uri string) (*MongoStore, error) {
 	client, err := mongo.NewClient(options.Client().ApplyURI(uri))
-	if err != nil { return nil, err }
-	if err := client.Connect(ctx); err != nil { return nil, err }
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
 	db := client.Database("fourinarow")
-	return &MongoStore{
-		Client:     client,
-		DB:         db,
-		PlayersCol: db.Collection("players"),
-		GamesCol:   db.Collection("games"),
-	}, nil
+	s := &MongoStore{
+		Client:      client,
+		DB:          db,
+		PlayersCol:  db.Collection("players"),
+		GamesCol:    db.Collection("games"),
+		ActiveCol:   db.Collection("active_games"),
+		AccountsCol: db.Collection("accounts"),
+	}
+	if err := s.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ensureIndexes creates the indexes the queries below rely on; it's a no-op
+// for the ones that already exist.
+func (s *MongoStore) ensureIndexes(ctx context.Context) error {
+	byPlayer := func(field string) mongo.IndexModel {
+		// PlayerGames, newest first, one index per side of the board
+		return mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}, {Key: "createdAt", Value: -1}, {Key: "gameId", Value: -1}}}
+	}
+	unique := func(field string) mongo.IndexModel {
+		return mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}}, Options: options.Index().SetUnique(true)}
+	}
+	for col, idx := range map[*mongo.Collection][]mongo.IndexModel{
+		s.GamesCol: {unique("gameId"), byPlayer("player1"), byPlayer("player2")},
+		// not unique: older databases may already have duplicate players
+		s.PlayersCol:  {{Keys: bson.D{{Key: "username", Value: 1}}}},
+		s.AccountsCol: {unique("username")},
+		s.ActiveCol:   {unique("gameId")},
+	} {
+		if _, err := col.Indexes().CreateMany(ctx, idx); err != nil {
+			return fmt.Errorf("create indexes on %s: %w", col.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.Client.Disconnect(ctx)
 }
 
 func (s *MongoStore) EnsurePlayer(ctx context.Context, username string) error {
@@ -35,17 +78,115 @@ func (s *MongoStore) EnsurePlayer(ctx context.Context, username string) error {
 		bson.M{"username": username},
 		bson.M{"$setOnInsert": bson.M{
 			"username": username, "wins": 0, "losses": 0, "draws": 0,
+			"rating": rating.DefaultRating, "rd": rating.DefaultRD,
+			"volatility": rating.DefaultVolatility, "ratedGames": 0,
 		}},
 		options.Update().SetUpsert(true),
 	)
 	return err
 }
 
+func (s *MongoStore) GetPlayer(ctx context.Context, username string) (models.Player, error) {
+	var p models.Player
+	err := s.PlayersCol.FindOne(ctx, bson.M{"username": username}).Decode(&p)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return p, fmt.Errorf("get player %s: %w", username, ErrNotFound)
+	}
+	if err != nil {
+		return p, fmt.Errorf("get player %s: %w", username, err)
+	}
+	return withRatingDefaults(p), nil
+}
+
+// rateRetries is how many times RateGame works a player's rating out again
+// when another game rated them in between.
+const rateRetries = 5
+
+// RateGame applies a Glicko-2 update for p1 vs p2, score1 being p1's result
+// (1, 0.5 or 0). "BOT" isn't stored: it always plays at botRating. There's
+// no transaction, since a standalone mongod has none: each player is written
+// with an update that only matches the record their new rating was worked
+// out from, and worked out again if another game got there first. Each side
+// is rated against the other's rating from before the game, as Glicko-2
+// wants, so the two writes don't depend on each other.
+func (s *MongoStore) RateGame(ctx context.Context, p1, p2 string, score1, botRating float64) ([]models.RatingChange, error) {
+	before := map[string]rating.Rating{}
+	for _, name := range []string{p1, p2} {
+		if name == BotName {
+			before[name] = botRatingAt(botRating)
+			continue
+		}
+		p, err := s.GetPlayer(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		before[name] = ratingOf(p)
+	}
+	var changes []models.RatingChange
+	for _, side := range []struct {
+		name, opp string
+		score     float64
+	}{{p1, p2, score1}, {p2, p1, 1 - score1}} {
+		if side.name == BotName {
+			continue
+		}
+		c, err := s.rateOne(ctx, side.name, before[side.opp], side.score)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// rateOne updates name's rating for a game scored score against opp.
+func (s *MongoStore) rateOne(ctx context.Context, name string, opp rating.Rating, score float64) (models.RatingChange, error) {
+	for try := 0; try < rateRetries; try++ {
+		// the record as stored, so the filter below matches it exactly
+		var p models.Player
+		err := s.PlayersCol.FindOne(ctx, bson.M{"username": name}).Decode(&p)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.RatingChange{}, fmt.Errorf("get player %s: %w", name, ErrNotFound)
+		}
+		if err != nil {
+			return models.RatingChange{}, fmt.Errorf("get player %s: %w", name, err)
+		}
+		old, now := ratingOf(p), rating.Update(ratingOf(p), opp, score)
+		res, err := s.PlayersCol.UpdateOne(ctx,
+			bson.M{"username": name, "ratedGames": orMissing(p.RatedGames), "rating": orMissing(p.Rating)},
+			bson.M{
+				"$set": bson.M{"rating": now.Rating, "rd": now.RD, "volatility": now.Volatility},
+				"$inc": bson.M{"ratedGames": 1},
+			})
+		if err != nil {
+			return models.RatingChange{}, fmt.Errorf("update rating %s: %w", name, err)
+		}
+		if res.MatchedCount == 1 {
+			return models.RatingChange{
+				Username: name,
+				Before:   old.Rating, After: now.Rating,
+				RDBefore: old.RD, RDAfter: now.RD,
+			}, nil
+		}
+	}
+	return models.RatingChange{}, fmt.Errorf("update rating %s: rated by other games %d times in a row", name, rateRetries)
+}
+
+// orMissing matches v, or no field at all when v is the zero value, as on
+// records from before the field existed.
+func orMissing[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return bson.M{"$in": bson.A{v, nil}}
+	}
+	return v
+}
+
 func (s *MongoStore) IncWinLoss(ctx context.Context, winner, loser string) error {
-	if winner != "BOT" {
+	if winner != BotName {
 		_, _ = s.PlayersCol.UpdateOne(ctx, bson.M{"username": winner}, bson.M{"$inc": bson.M{"wins": 1}})
 	}
-	if loser != "BOT" {
+	if loser != BotName {
 		_, _ = s.PlayersCol.UpdateOne(ctx, bson.M{"username": loser}, bson.M{"$inc": bson.M{"losses": 1}})
 	}
 	return nil
@@ -65,29 +206,200 @@ func (s *MongoStore) InsertGame(ctx context.Context, g models.GameDoc) error {
 	return err
 }
 
-func (s *MongoStore) TopPlayers(ctx context.Context, limit int64) ([]models.Player, error) {
-    opts := options.Find().SetSort(bson.D{
-        {Key: "wins",  Value: -1},
-        {Key: "draws", Value: -1},
-    }).SetLimit(limit)
-
-    cur, err := s.PlayersCol.Find(ctx, bson.M{}, opts)
-    if err != nil {
-        return nil, fmt.Errorf("find players: %w", err)
-    }
-    defer cur.Close(ctx)
-
-    var out []models.Player
-    for cur.Next(ctx) {
-        var p models.Player
-        if err := cur.Decode(&p); err != nil {
-            return nil, fmt.Errorf("decode player: %w", err)
-        }
-        out = append(out, p)
-    }
-    if err := cur.Err(); err != nil {
-        return nil, fmt.Errorf("cursor: %w", err)
-    }
-    return out, nil
+func (s *MongoStore) SaveActiveGame(ctx context.Context, g models.ActiveGame) error {
+	g.UpdatedAt = time.Now()
+	_, err := s.ActiveCol.ReplaceOne(ctx, bson.M{"gameId": g.GameID}, g, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("save active game %s: %w", g.GameID, err)
+	}
+	return nil
+}
+
+func (s *MongoStore) DeleteActiveGame(ctx context.Context, gameID string) error {
+	_, err := s.ActiveCol.DeleteOne(ctx, bson.M{"gameId": gameID})
+	return err
+}
+
+func (s *MongoStore) ActiveGames(ctx context.Context) ([]models.ActiveGame, error) {
+	cur, err := s.ActiveCol.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("find active games: %w", err)
+	}
+	var out []models.ActiveGame
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, fmt.Errorf("decode active games: %w", err)
+	}
+	return out, nil
+}
+
+// TopPlayers is the leaderboard: highest rating first, only counting players
+// with at least minGames rated games.
+func (s *MongoStore) TopPlayers(ctx context.Context, limit int64, minGames int) ([]models.Player, error) {
+	opts := options.Find().SetSort(bson.D{
+		{Key: "rating", Value: -1},
+		{Key: "wins", Value: -1},
+	}).SetLimit(limit)
+
+	filter := bson.M{}
+	if minGames > 0 {
+		filter["ratedGames"] = bson.M{"$gte": minGames}
+	}
+	cur, err := s.PlayersCol.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find players: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	var out []models.Player
+	for cur.Next(ctx) {
+		var p models.Player
+		if err := cur.Decode(&p); err != nil {
+			return nil, fmt.Errorf("decode player: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, fmt.Errorf("cursor: %w", err)
+	}
+	return out, nil
+}
+
+// CreateAccount inserts a only if the username isn't registered yet; the
+// upsert makes the check and the insert one operation.
+func (s *MongoStore) CreateAccount(ctx context.Context, a models.Account) error {
+	a.CreatedAt = time.Now()
+	res, err := s.AccountsCol.UpdateOne(ctx,
+		bson.M{"username": a.Username},
+		bson.M{"$setOnInsert": a},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("create account %s: %w", a.Username, err)
+	}
+	if res.UpsertedCount == 0 {
+		return fmt.Errorf("create account %s: %w", a.Username, ErrTaken)
+	}
+	return nil
+}
+
+func (s *MongoStore) GetAccount(ctx context.Context, username string) (models.Account, error) {
+	var a models.Account
+	err := s.AccountsCol.FindOne(ctx, bson.M{"username": username}).Decode(&a)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return a, fmt.Errorf("get account %s: %w", username, ErrNotFound)
+	}
+	if err != nil {
+		return a, fmt.Errorf("get account %s: %w", username, err)
+	}
+	return a, nil
+}
+
+func (s *MongoStore) GetGame(ctx context.Context, gameID string) (models.GameDoc, error) {
+	var g models.GameDoc
+	err := s.GamesCol.FindOne(ctx, bson.M{"gameId": gameID}).Decode(&g)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return g, fmt.Errorf("get game %s: %w", gameID, ErrNotFound)
+	}
+	if err != nil {
+		return g, fmt.Errorf("get game %s: %w", gameID, err)
+	}
+	return g, nil
+}
+
+// AnnotateGame only sets notes on a game that has none, so a second run
+// can't count the same moves twice.
+func (s *MongoStore) AnnotateGame(ctx context.Context, gameID string, notes []models.MoveNote) error {
+	g, err := s.GetGame(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	res, err := s.GamesCol.UpdateOne(ctx,
+		bson.M{"gameId": gameID, "notes": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"notes": notes}},
+	)
+	if err != nil {
+		return fmt.Errorf("annotate game %s: %w", gameID, err)
+	}
+	if res.ModifiedCount == 0 {
+		return nil
+	}
+	for name, t := range noteTallies(g, notes) {
+		_, err := s.PlayersCol.UpdateOne(ctx, bson.M{"username": name}, bson.M{"$inc": bson.M{
+			"annotated.best":       t.Best,
+			"annotated.inaccuracy": t.Inaccuracy,
+			"annotated.mistake":    t.Mistake,
+			"annotated.blunder":    t.Blunder,
+		}})
+		if err != nil {
+			return fmt.Errorf("annotate game %s: player %s: %w", gameID, name, err)
+		}
+	}
+	return nil
+}
+
+// PlayerGames is GameQuery as a find on the player1/player2 indexes.
+func (s *MongoStore) PlayerGames(ctx context.Context, username string, q GameQuery) ([]models.GameDoc, error) {
+	either := func(a, b any) bson.M {
+		return bson.M{"$or": bson.A{bson.M{"player1": a, "player2": b}, bson.M{"player1": b, "player2": a}}}
+	}
+	and := bson.A{either(username, bson.M{"$exists": true})}
+	if q.Opponent != "" {
+		and = append(and, either(username, q.Opponent))
+	}
+	if q.VsBot != nil {
+		if *q.VsBot {
+			and = append(and, either(username, BotName))
+		} else {
+			and = append(and, bson.M{"player1": bson.M{"$ne": BotName}, "player2": bson.M{"$ne": BotName}})
+		}
+	}
+	switch q.Result {
+	case "win":
+		and = append(and, bson.M{"winner": username})
+	case "loss":
+		and = append(and, bson.M{"winner": bson.M{"$nin": bson.A{username, "Draw"}}})
+	case "draw":
+		and = append(and, bson.M{"winner": "Draw"})
+	}
+	if !q.From.IsZero() {
+		and = append(and, bson.M{"createdAt": bson.M{"$gte": q.From}})
+	}
+	if !q.To.IsZero() {
+		and = append(and, bson.M{"createdAt": bson.M{"$lt": q.To}})
+	}
+	if c := q.Before; c != nil {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"createdAt": bson.M{"$lt": c.CreatedAt}},
+			bson.M{"createdAt": c.CreatedAt, "gameId": bson.M{"$lt": c.GameID}},
+		}})
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "gameId", Value: -1}})
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	cur, err := s.GamesCol.Find(ctx, bson.M{"$and": and}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("find games of %s: %w", username, err)
+	}
+	var out []models.GameDoc
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, fmt.Errorf("find games of %s: %w", username, err)
+	}
+	return out, nil
 }
 
+func (s *MongoStore) HeadToHead(ctx context.Context, a, b string) (models.HeadToHead, error) {
+	// only what the tally needs, not every board and move list
+	cur, err := s.GamesCol.Find(ctx,
+		bson.M{"$or": bson.A{bson.M{"player1": a, "player2": b}, bson.M{"player1": b, "player2": a}}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetProjection(bson.M{"winner": 1, "createdAt": 1}),
+	)
+	if err != nil {
+		return models.HeadToHead{}, fmt.Errorf("head to head %s/%s: %w", a, b, err)
+	}
+	var games []models.GameDoc
+	if err := cur.All(ctx, &games); err != nil {
+		return models.HeadToHead{}, fmt.Errorf("head to head %s/%s: %w", a, b, err)
+	}
+	return headToHead(a, b, games), nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"time"
+
+	"github.com/yourname/fourinarow/internal/models"
+)
+
+// GameQuery picks out one player's finished games. Results come newest
+// first; pass the last one back as Before for the next page.
+type GameQuery struct {
+	Opponent string    // only games against this player
+	Result   string    // "win", "loss" or "draw", from the player's side; empty for all
+	From, To time.Time // CreatedAt in [From, To); zero leaves that end open
+	VsBot    *bool     // only bot games, or only games between people
+	Before   *GameCursor
+	Limit    int // 0 means no limit
+}
+
+// GameCursor is a position in a player's games, newest first. CreatedAt
+// alone isn't unique, so GameID breaks ties.
+type GameCursor struct {
+	CreatedAt time.Time
+	GameID    string
+}
+
+// CursorOf is the cursor just past g.
+func CursorOf(g models.GameDoc) GameCursor {
+	return GameCursor{CreatedAt: g.CreatedAt, GameID: g.GameID}
+}
+
+// after reports whether g comes after c, i.e. is older.
+func (c GameCursor) after(g models.GameDoc) bool {
+	if !g.CreatedAt.Equal(c.CreatedAt) {
+		return g.CreatedAt.Before(c.CreatedAt)
+	}
+	return g.GameID < c.GameID
+}
+
+// opponentOf is the other player in g, or "" if username didn't play it.
+func opponentOf(g models.GameDoc, username string) string {
+	switch username {
+	case g.Player1:
+		return g.Player2
+	case g.Player2:
+		return g.Player1
+	}
+	return ""
+}
+
+// matches is GameQuery for the stores that filter in Go; MongoStore
+// builds the same thing as a query.
+func (q GameQuery) matches(g models.GameDoc, username string) bool {
+	opp := opponentOf(g, username)
+	if opp == "" || q.Opponent != "" && opp != q.Opponent {
+		return false
+	}
+	if q.VsBot != nil && *q.VsBot != (opp == BotName) {
+		return false
+	}
+	switch q.Result {
+	case "win":
+		if g.Winner != username {
+			return false
+		}
+	case "loss":
+		if g.Winner == username || g.Winner == "Draw" {
+			return false
+		}
+	case "draw":
+		if g.Winner != "Draw" {
+			return false
+		}
+	}
+	if !q.From.IsZero() && g.CreatedAt.Before(q.From) || !q.To.IsZero() && !g.CreatedAt.Before(q.To) {
+		return false
+	}
+	return q.Before == nil || q.Before.after(g)
+}
+
+// headToHead tallies games between a and b, newest first.
+func headToHead(a, b string, games []models.GameDoc) models.HeadToHead {
+	h := models.HeadToHead{PlayerA: a, PlayerB: b, Games: len(games)}
+	for _, g := range games {
+		switch g.Winner {
+		case a:
+			h.WinsA++
+		case b:
+			h.WinsB++
+		default:
+			h.Draws++
+		}
+	}
+	if len(games) > 0 {
+		h.LastPlayed = games[0].CreatedAt
+	}
+	return h
+}
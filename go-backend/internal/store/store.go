@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"github.com/yourname/fourinarow/internal/rating"
+)
+
+// Store is everything the server persists. MongoStore is the production
+// one, MemoryStore is for tests and throwaway servers, BoltStore keeps it
+// all in one local file for self-hosting.
+type Store interface {
+	EnsurePlayer(ctx context.Context, username string) error
+	GetPlayer(ctx context.Context, username string) (models.Player, error)
+	IncWinLoss(ctx context.Context, winner, loser string) error
+	IncDraws(ctx context.Context, users []string) error
+	RateGame(ctx context.Context, p1, p2 string, score1, botRating float64) ([]models.RatingChange, error)
+	InsertGame(ctx context.Context, g models.GameDoc) error
+	TopPlayers(ctx context.Context, limit int64, minGames int) ([]models.Player, error)
+
+	// Finished games, see GameQuery.
+	GetGame(ctx context.Context, gameID string) (models.GameDoc, error)
+	PlayerGames(ctx context.Context, username string, q GameQuery) ([]models.GameDoc, error)
+	HeadToHead(ctx context.Context, a, b string) (models.HeadToHead, error)
+	// AnnotateGame stores the engine's notes on a game's moves and adds them
+	// to both players' tallies, once; a game that has notes keeps them.
+	AnnotateGame(ctx context.Context, gameID string, notes []models.MoveNote) error
+
+	// Registered logins; a username without one can only be played as a guest.
+	CreateAccount(ctx context.Context, a models.Account) error // ErrTaken if the name is registered
+	GetAccount(ctx context.Context, username string) (models.Account, error)
+
+	// Games in progress, checkpointed so they survive a restart.
+	SaveActiveGame(ctx context.Context, g models.ActiveGame) error // insert or replace
+	DeleteActiveGame(ctx context.Context, gameID string) error
+	ActiveGames(ctx context.Context) ([]models.ActiveGame, error)
+
+	Close(ctx context.Context) error
+}
+
+// ErrNotFound is returned (possibly wrapped) when a lookup matches nothing.
+var ErrNotFound = errors.New("not found")
+
+// ErrTaken is returned by CreateAccount when the username is registered.
+var ErrTaken = errors.New("username taken")
+
+// BotName is the pseudo-player seated for bot games; it's never stored.
+const BotName = "BOT"
+
+// BotRD is the fixed deviation the bot is rated at when bot games count.
+const BotRD = 50.0
+
+func newPlayer(username string) models.Player {
+	r := rating.New()
+	return models.Player{Username: username, Rating: r.Rating, RD: r.RD, Volatility: r.Volatility}
+}
+
+// withRatingDefaults fills in players created before ratings existed.
+func withRatingDefaults(p models.Player) models.Player {
+	if p.RD == 0 {
+		r := rating.New()
+		p.Rating, p.RD, p.Volatility = r.Rating, r.RD, r.Volatility
+	}
+	return p
+}
+
+// ratingOf is p's Glicko-2 rating.
+func ratingOf(p models.Player) rating.Rating {
+	p = withRatingDefaults(p)
+	return rating.Rating{Rating: p.Rating, RD: p.RD, Volatility: p.Volatility}
+}
+
+// botRatingAt is the bot's rating when it plays at r.
+func botRatingAt(r float64) rating.Rating {
+	return rating.Rating{Rating: r, RD: BotRD, Volatility: rating.DefaultVolatility}
+}
+
+// rateGame is the Glicko-2 step shared by every store: load both ratings,
+// update them against each other and save whichever isn't the bot.
+func rateGame(p1, p2 string, score1, botRating float64,
+	load func(name string) (models.Player, error),
+	save func(name string, r rating.Rating) error,
+) ([]models.RatingChange, error) {
+	get := func(name string) (rating.Rating, error) {
+		if name == BotName {
+			return botRatingAt(botRating), nil
+		}
+		p, err := load(name)
+		if err != nil {
+			return rating.Rating{}, err
+		}
+		return ratingOf(p), nil
+	}
+	r1, err := get(p1)
+	if err != nil {
+		return nil, err
+	}
+	r2, err := get(p2)
+	if err != nil {
+		return nil, err
+	}
+	var changes []models.RatingChange
+	for _, u := range []struct {
+		name          string
+		before, after rating.Rating
+	}{
+		{p1, r1, rating.Update(r1, r2, score1)},
+		{p2, r2, rating.Update(r2, r1, 1-score1)},
+	} {
+		if u.name == BotName {
+			continue
+		}
+		if err := save(u.name, u.after); err != nil {
+			return nil, err
+		}
+		changes = append(changes, models.RatingChange{
+			Username: u.name,
+			Before:   u.before.Rating, After: u.after.Rating,
+			RDBefore: u.before.RD, RDAfter: u.after.RD,
+		})
+	}
+	return changes, nil
+}
+
+// noteTallies splits notes between g's players by who made each move,
+// leaving out the bot.
+func noteTallies(g models.GameDoc, notes []models.MoveNote) map[string]models.MoveTally {
+	out := map[string]models.MoveTally{}
+	for i, n := range notes {
+		if i >= len(g.Moves) {
+			break
+		}
+		name := g.Player1
+		if g.Moves[i].Player == "Y" {
+			name = g.Player2
+		}
+		if name == BotName {
+			continue
+		}
+		t := out[name]
+		t.Add(n.Class)
+		out[name] = t
+	}
+	return out
+}
+
+// leaderboard sorts and trims players the way MongoStore.TopPlayers does.
+func leaderboard(players []models.Player, limit int64, minGames int) []models.Player {
+	out := players[:0:0]
+	for _, p := range players {
+		if minGames <= 0 || p.RatedGames >= minGames {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Rating != out[j].Rating {
+			return out[i].Rating > out[j].Rating
+		}
+		return out[i].Wins > out[j].Wins
+	})
+	if limit > 0 && int64(len(out)) > limit {
+		out = out[:limit]
+	}
+	return out
+}
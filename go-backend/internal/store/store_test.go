@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/yourname/fourinarow/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Every Store is run through the same checks; MongoStore needs a server,
+// so it isn't here.
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store { return NewMemoryStore() })
+}
+
+func TestBoltStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		s, err := NewBoltStore(filepath.Join(t.TempDir(), "fourinarow.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close(context.Background()) })
+		return s
+	})
+}
+
+func testStore(t *testing.T, open func(t *testing.T) Store) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		run  func(t *testing.T, s Store)
+	}{
+		{"players", func(t *testing.T, s Store) {
+			if _, err := s.GetPlayer(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("GetPlayer before EnsurePlayer: %v, want ErrNotFound", err)
+			}
+			mustDo(t, s.EnsurePlayer(ctx, "alice"))
+			mustDo(t, s.EnsurePlayer(ctx, "bob"))
+			mustDo(t, s.IncWinLoss(ctx, "alice", "bob"))
+			mustDo(t, s.EnsurePlayer(ctx, "alice")) // keeps the record
+			mustDo(t, s.IncDraws(ctx, []string{"alice", "bob"}))
+			// nobody stored for the bot or guests, and that's not an error
+			mustDo(t, s.IncWinLoss(ctx, BotName, "alice"))
+			mustDo(t, s.IncDraws(ctx, []string{"guest-zed"}))
+
+			a, b := player(t, s, "alice"), player(t, s, "bob")
+			if a.Wins != 1 || a.Losses != 1 || a.Draws != 1 {
+				t.Errorf("alice: %+v", a)
+			}
+			if b.Wins != 0 || b.Losses != 1 || b.Draws != 1 {
+				t.Errorf("bob: %+v", b)
+			}
+			if a.Rating != 1500 || a.RD == 0 {
+				t.Errorf("new player's rating: %+v", a)
+			}
+		}},
+
+		{"ratings", func(t *testing.T, s Store) {
+			mustDo(t, s.EnsurePlayer(ctx, "alice"))
+			mustDo(t, s.EnsurePlayer(ctx, "bob"))
+			changes, err := s.RateGame(ctx, "alice", "bob", 1, 0)
+			mustDo(t, err)
+			if len(changes) != 2 || changes[0].After <= changes[0].Before || changes[1].After >= changes[1].Before {
+				t.Fatalf("changes: %+v", changes)
+			}
+			if a := player(t, s, "alice"); a.Rating != changes[0].After || a.RatedGames != 1 {
+				t.Errorf("alice after a win: %+v", a)
+			}
+
+			// the bot is rated against but never stored
+			changes, err = s.RateGame(ctx, BotName, "bob", 0, 1500)
+			mustDo(t, err)
+			if len(changes) != 1 || changes[0].Username != "bob" {
+				t.Fatalf("bot game changes: %+v", changes)
+			}
+			if _, err := s.GetPlayer(ctx, BotName); !errors.Is(err, ErrNotFound) {
+				t.Errorf("bot stored: %v", err)
+			}
+			if _, err := s.RateGame(ctx, "alice", "nobody", 1, 0); err == nil {
+				t.Error("rated a game against a player who isn't stored")
+			}
+		}},
+
+		{"leaderboard", func(t *testing.T, s Store) {
+			for _, name := range []string{"low", "high", "fresh"} {
+				mustDo(t, s.EnsurePlayer(ctx, name))
+			}
+			for i := 0; i < 3; i++ {
+				_, err := s.RateGame(ctx, "high", "low", 1, 0)
+				mustDo(t, err)
+			}
+			top, err := s.TopPlayers(ctx, 10, 0)
+			mustDo(t, err)
+			if names := usernames(top); len(names) != 3 || names[0] != "high" || names[2] != "low" {
+				t.Errorf("top players: %v", names)
+			}
+			top, err = s.TopPlayers(ctx, 1, 1)
+			mustDo(t, err)
+			if names := usernames(top); len(names) != 1 || names[0] != "high" {
+				t.Errorf("top 1 with a rated game: %v", names)
+			}
+		}},
+
+		{"games", func(t *testing.T, s Store) {
+			if _, err := s.GetGame(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("GetGame of nothing: %v, want ErrNotFound", err)
+			}
+			games := []models.GameDoc{
+				{GameID: "g1", Player1: "alice", Player2: "bob", Winner: "alice", Reason: "connect"},
+				{GameID: "g2", Player1: "bob", Player2: "alice", Winner: "Draw", Reason: "boardFull"},
+				{GameID: "g3", Player1: "alice", Player2: BotName, Winner: BotName, Reason: "resign"},
+				{GameID: "g4", Player1: "carol", Player2: "alice", Winner: "alice", Reason: "timeout"},
+				{GameID: "g5", Player1: "bob", Player2: "carol", Winner: "bob", Reason: "connect"},
+			}
+			for _, g := range games {
+				mustDo(t, s.InsertGame(ctx, g))
+			}
+			g, err := s.GetGame(ctx, "g2")
+			mustDo(t, err)
+			if g.Winner != "Draw" || g.Reason != "boardFull" || g.CreatedAt.IsZero() {
+				t.Errorf("GetGame: %+v", g)
+			}
+
+			yes, no := true, false
+			for _, q := range []struct {
+				name  string
+				query GameQuery
+				want  []string // newest first
+			}{
+				{"all", GameQuery{}, []string{"g4", "g3", "g2", "g1"}},
+				{"wins", GameQuery{Result: "win"}, []string{"g4", "g1"}},
+				{"losses", GameQuery{Result: "loss"}, []string{"g3"}},
+				{"draws", GameQuery{Result: "draw"}, []string{"g2"}},
+				{"against bob", GameQuery{Opponent: "bob"}, []string{"g2", "g1"}},
+				{"bot games", GameQuery{VsBot: &yes}, []string{"g3"}},
+				{"people", GameQuery{VsBot: &no}, []string{"g4", "g2", "g1"}},
+				{"first page", GameQuery{Limit: 3}, []string{"g4", "g3", "g2"}},
+			} {
+				got, err := s.PlayerGames(ctx, "alice", q.query)
+				mustDo(t, err)
+				if ids := gameIDs(got); !slices.Equal(ids, q.want) {
+					t.Errorf("%s: %v, want %v", q.name, ids, q.want)
+				}
+			}
+
+			// paging with the last game as the cursor picks up where it left off
+			first, err := s.PlayerGames(ctx, "alice", GameQuery{Limit: 2})
+			mustDo(t, err)
+			cur := CursorOf(first[len(first)-1])
+			rest, err := s.PlayerGames(ctx, "alice", GameQuery{Limit: 2, Before: &cur})
+			mustDo(t, err)
+			if ids := append(gameIDs(first), gameIDs(rest)...); !slices.Equal(ids, []string{"g4", "g3", "g2", "g1"}) {
+				t.Errorf("two pages: %v", ids)
+			}
+
+			h, err := s.HeadToHead(ctx, "alice", "bob")
+			mustDo(t, err)
+			if h.Games != 2 || h.WinsA != 1 || h.WinsB != 0 || h.Draws != 1 || h.LastPlayed.IsZero() {
+				t.Errorf("head to head: %+v", h)
+			}
+		}},
+
+		{"annotations", func(t *testing.T, s Store) {
+			mustDo(t, s.EnsurePlayer(ctx, "alice"))
+			moves := []models.Move{{Player: "R", Col: 3}, {Player: "Y", Col: 3}, {Player: "R", Col: 2}, {Player: "Y", Col: 0}, {Player: "R", Col: 1}}
+			mustDo(t, s.InsertGame(ctx, models.GameDoc{GameID: "g1", Player1: "alice", Player2: BotName, Winner: "alice", Moves: moves}))
+			notes := []models.MoveNote{
+				{Class: models.NoteBest, Best: 3},
+				{Class: models.NoteBlunder, Best: 2},
+				{Class: models.NoteInaccuracy, Best: 4},
+				{Class: models.NoteMistake, Best: 2},
+				{Class: models.NoteUnknown, Best: 4},
+			}
+			if err := s.AnnotateGame(ctx, "nope", notes); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("annotating nothing: %v, want ErrNotFound", err)
+			}
+			mustDo(t, s.AnnotateGame(ctx, "g1", notes))
+			// a second run changes nothing
+			mustDo(t, s.AnnotateGame(ctx, "g1", []models.MoveNote{{Class: models.NoteBlunder}}))
+
+			g, err := s.GetGame(ctx, "g1")
+			mustDo(t, err)
+			if len(g.Notes) != 5 || g.Notes[1].Class != models.NoteBlunder || g.Notes[2].Best != 4 {
+				t.Errorf("notes: %+v", g.Notes)
+			}
+			// only alice's moves, not the unknown one, and the bot isn't stored
+			want := models.MoveTally{Best: 1, Inaccuracy: 1}
+			if a := player(t, s, "alice"); a.Annotated != want {
+				t.Errorf("alice's tally: %+v, want %+v", a.Annotated, want)
+			}
+		}},
+
+		{"accounts", func(t *testing.T, s Store) {
+			if _, err := s.GetAccount(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("GetAccount before CreateAccount: %v, want ErrNotFound", err)
+			}
+			mustDo(t, s.CreateAccount(ctx, models.Account{Username: "alice", PasswordHash: "hash"}))
+			if err := s.CreateAccount(ctx, models.Account{Username: "alice", PasswordHash: "other"}); !errors.Is(err, ErrTaken) {
+				t.Fatalf("second CreateAccount: %v, want ErrTaken", err)
+			}
+			a, err := s.GetAccount(ctx, "alice")
+			mustDo(t, err)
+			if a.PasswordHash != "hash" || a.CreatedAt.IsZero() {
+				t.Errorf("account: %+v", a)
+			}
+		}},
+
+		{"active games", func(t *testing.T, s Store) {
+			g := models.ActiveGame{GameID: "g1", Player1: "alice", Player2: "bob", Turn: "R", Rows: 6, Cols: 7, Connect: 4}
+			mustDo(t, s.SaveActiveGame(ctx, g))
+			g.Turn, g.Moves = "Y", []models.Move{{Player: "R", Col: 3}}
+			mustDo(t, s.SaveActiveGame(ctx, g)) // replaces it
+			mustDo(t, s.SaveActiveGame(ctx, models.ActiveGame{GameID: "g2", Player1: "carol", Player2: BotName, Engine: "hard"}))
+
+			active, err := s.ActiveGames(ctx)
+			mustDo(t, err)
+			if len(active) != 2 {
+				t.Fatalf("%d active games, want 2", len(active))
+			}
+			for _, a := range active {
+				if a.GameID == "g1" && (a.Turn != "Y" || len(a.Moves) != 1 || a.UpdatedAt.IsZero()) {
+					t.Errorf("g1 not replaced: %+v", a)
+				}
+			}
+			mustDo(t, s.DeleteActiveGame(ctx, "g1"))
+			mustDo(t, s.DeleteActiveGame(ctx, "g1")) // already gone is fine
+			active, err = s.ActiveGames(ctx)
+			mustDo(t, err)
+			if len(active) != 1 || active[0].GameID != "g2" {
+				t.Errorf("after delete: %+v", active)
+			}
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) { tc.run(t, open(t)) })
+	}
+}
+
+// MongoStore.RateGame only writes a rating over the record it read; a
+// record from before ratings has no fields to compare, so zero must match
+// their absence too.
+func TestOrMissing(t *testing.T) {
+	if got, ok := orMissing(0).(bson.M); !ok || !reflect.DeepEqual(got["$in"], bson.A{0, nil}) {
+		t.Errorf("orMissing(0) = %v", orMissing(0))
+	}
+	if got := orMissing(1502.5); got != 1502.5 {
+		t.Errorf("orMissing(1502.5) = %v", got)
+	}
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func player(t *testing.T, s Store, name string) models.Player {
+	t.Helper()
+	p, err := s.GetPlayer(context.Background(), name)
+	mustDo(t, err)
+	return p
+}
+
+func usernames(players []models.Player) []string {
+	var out []string
+	for _, p := range players {
+		out = append(out, p.Username)
+	}
+	return out
+}
+
+func gameIDs(games []models.GameDoc) []string {
+	var out []string
+	for _, g := range games {
+		out = append(out, g.GameID)
+	}
+	return out
+}